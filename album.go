@@ -0,0 +1,199 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultQuietPeriod is the time a MediaGroupCollector waits for more messages of a media group.
+const DefaultQuietPeriod = time.Second
+
+// MediaGroupCollector gathers the messages of a media group, which Telegram delivers as separate
+// updates, into a single update with the Album field set. Its middleware holds back new messages
+// and channel posts with a media group identifier until no message of the group arrived for
+// the quiet period, and then passes the album on.
+//
+// For updates processed by a Dispatcher, the album is queued to the dispatcher as a new update,
+// processed in order with the other updates of the chat, and the held messages are not done,
+// e.g. confirmed by a Poller, until the album is processed. The dispatcher flushes the collector
+// when it shuts down. Otherwise, the album is passed to the next handler on its own goroutine
+// and its errors are passed to OnError.
+type MediaGroupCollector struct {
+	// QuietPeriod is the time to wait for more messages of a media group, DefaultQuietPeriod if zero.
+	QuietPeriod time.Duration
+	// OnError is called with the errors returned by the handler of an album processed outside
+	// a dispatcher, except ErrNotHandled. If nil, the errors are logged with the standard logger.
+	OnError func(c *Context, err error)
+
+	mu     sync.Mutex
+	groups map[mediaGroupKey]*pendingAlbum
+	wg     sync.WaitGroup
+}
+
+type mediaGroupKey struct {
+	bot     *Bot
+	chatId  int64
+	groupId string
+}
+
+type pendingAlbum struct {
+	ctx        context.Context
+	next       Handler
+	dispatcher *Dispatcher
+	// holds keep the jobs of the messages processed by the dispatcher from completing.
+	holds    []func(processed bool)
+	updateId int
+	messages []*Message
+	// gen is incremented with each message, so that a timer firing for an earlier message is ignored.
+	gen   int
+	timer *time.Timer
+}
+
+// Middleware returns the middleware that collects media groups.
+func (mc *MediaGroupCollector) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(c *Context) error {
+			msg := c.Update.Message
+
+			if msg == nil {
+				msg = c.Update.ChannelPost
+			}
+
+			if msg == nil || msg.MediaGroupId == "" || msg.Chat == nil {
+				return next.HandleUpdate(c)
+			}
+
+			mc.add(c, next, msg)
+
+			return nil
+		})
+	}
+}
+
+func (mc *MediaGroupCollector) add(c *Context, next Handler, msg *Message) {
+	key := mediaGroupKey{bot: c.Bot, chatId: msg.Chat.Id, groupId: msg.MediaGroupId}
+	quiet := mc.QuietPeriod
+
+	if quiet <= 0 {
+		quiet = DefaultQuietPeriod
+	}
+
+	if c.dispatcher != nil {
+		c.dispatcher.register(mc)
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if mc.groups == nil {
+		mc.groups = make(map[mediaGroupKey]*pendingAlbum)
+	}
+
+	album, ok := mc.groups[key]
+
+	if !ok {
+		album = &pendingAlbum{}
+		mc.groups[key] = album
+	} else {
+		album.timer.Stop()
+	}
+
+	album.gen++
+	gen := album.gen
+	album.timer = time.AfterFunc(quiet, func() {
+		mc.release(key, gen)
+	})
+
+	album.ctx = c.Context
+	album.next = next
+	album.messages = append(album.messages, msg)
+
+	if c.dispatcher != nil {
+		album.dispatcher = c.dispatcher
+		album.holds = append(album.holds, c.dispatcher.hold(c.job))
+	}
+
+	if c.Update.UpdateId > album.updateId {
+		album.updateId = c.Update.UpdateId
+	}
+}
+
+// release passes the album of the media group on, unless another message of the group arrived
+// after the generation gen.
+func (mc *MediaGroupCollector) release(key mediaGroupKey, gen int) {
+	mc.mu.Lock()
+	album, ok := mc.groups[key]
+
+	if !ok || album.gen != gen {
+		mc.mu.Unlock()
+
+		return
+	}
+
+	delete(mc.groups, key)
+	album.timer.Stop()
+	mc.wg.Add(1)
+	mc.mu.Unlock()
+
+	defer mc.wg.Done()
+
+	sort.SliceStable(album.messages, func(i, j int) bool {
+		return album.messages[i].MessageId < album.messages[j].MessageId
+	})
+
+	u := &Update{
+		UpdateId: album.updateId,
+		Album:    album.messages,
+	}
+
+	if album.dispatcher != nil {
+		album.dispatcher.redispatch(key.bot, u, func(processed bool) {
+			for _, release := range album.holds {
+				release(processed)
+			}
+		})
+
+		return
+	}
+
+	c := NewContext(album.ctx, key.bot, u)
+	err := chain(album.next, []Middleware{Recover()}).HandleUpdate(c)
+
+	if err == nil || err == ErrNotHandled {
+		return
+	}
+
+	if mc.OnError != nil {
+		mc.OnError(c, err)
+	} else {
+		log.Printf("%v", err)
+	}
+}
+
+// Flush passes the albums collected so far on without waiting for the quiet period, and waits until
+// they are queued to their dispatcher or, outside a dispatcher, handled. Call it before shutting down
+// when the collector is not used with a dispatcher.
+func (mc *MediaGroupCollector) Flush() {
+	mc.mu.Lock()
+	gens := make(map[mediaGroupKey]int, len(mc.groups))
+
+	for key, album := range mc.groups {
+		gens[key] = album.gen
+	}
+
+	mc.mu.Unlock()
+
+	for key, gen := range gens {
+		mc.release(key, gen)
+	}
+
+	mc.wg.Wait()
+}
@@ -0,0 +1,153 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+type albumRecorder struct {
+	mu     sync.Mutex
+	albums [][]int
+}
+
+func (r *albumRecorder) handle(c *tgbot.Context) error {
+	var ids []int
+
+	for _, msg := range c.Update.Album {
+		ids = append(ids, msg.MessageId)
+	}
+
+	r.mu.Lock()
+	r.albums = append(r.albums, ids)
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *albumRecorder) get() [][]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([][]int(nil), r.albums...)
+}
+
+func albumRouter(mc *tgbot.MediaGroupCollector, rec *albumRecorder) *tgbot.Router {
+	router := tgbot.NewRouter()
+	router.Use(mc.Middleware())
+	router.OnAlbum(rec.handle)
+
+	return router
+}
+
+func albumUpdate(updateId, messageId int, groupId string) *tgbot.Update {
+	return &tgbot.Update{
+		UpdateId: updateId,
+		Message: &tgbot.Message{
+			MessageId:    messageId,
+			Chat:         &tgbot.Chat{Id: 42, Type: "private"},
+			MediaGroupId: groupId,
+		},
+	}
+}
+
+func TestMediaGroupCollectorIgnoresStaleTimers(t *testing.T) {
+	rec := &albumRecorder{}
+	mc := &tgbot.MediaGroupCollector{QuietPeriod: time.Hour}
+	router := albumRouter(mc, rec)
+	bot := &tgbot.Bot{Token: "123:abc"}
+
+	router.HandleUpdate(tgbot.NewContext(context.Background(), bot, albumUpdate(1, 11, "g")))
+	router.HandleUpdate(tgbot.NewContext(context.Background(), bot, albumUpdate(2, 12, "g")))
+
+	// The timer set for the first message fires while the second one is collected.
+	mc.FireQuietTimer(bot, 42, "g", 1)
+
+	if albums := rec.get(); len(albums) != 0 {
+		t.Fatalf("stale timer released %v", albums)
+	}
+
+	router.HandleUpdate(tgbot.NewContext(context.Background(), bot, albumUpdate(3, 13, "g")))
+	mc.Flush()
+
+	if albums, want := rec.get(), [][]int{{11, 12, 13}}; !reflect.DeepEqual(albums, want) {
+		t.Errorf("albums = %v, want %v", albums, want)
+	}
+}
+
+func TestMediaGroupCollectorIsFlushedByShutdown(t *testing.T) {
+	rec := &albumRecorder{}
+	mc := &tgbot.MediaGroupCollector{QuietPeriod: time.Hour}
+	d := tgbot.NewDispatcher(albumRouter(mc, rec), 2, 4)
+	bot := &tgbot.Bot{Token: "123:abc"}
+
+	for _, u := range []*tgbot.Update{albumUpdate(1, 12, "g"), albumUpdate(2, 11, "g")} {
+		if err := d.Dispatch(context.Background(), bot, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := d.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if albums, want := rec.get(), [][]int{{11, 12}}; !reflect.DeepEqual(albums, want) {
+		t.Errorf("albums = %v, want %v", albums, want)
+	}
+}
+
+func TestMediaGroupCollectorDelaysConfirmationUntilAlbumIsHandled(t *testing.T) {
+	queue := &updateQueue{}
+	newStubApi(t, queue.handle)
+
+	for i := 1; i <= 3; i++ {
+		queue.updates = append(queue.updates, *albumUpdate(i, 10+i, "g"))
+	}
+
+	rec := &albumRecorder{}
+	mc := &tgbot.MediaGroupCollector{QuietPeriod: time.Hour}
+	d := tgbot.NewDispatcher(albumRouter(mc, rec), 2, 4)
+	defer d.Shutdown(context.Background())
+
+	store := tgbot.NewStorageOffsetStore(tgbot.NewMemoryStorage(), "offset")
+	p := &tgbot.Poller{Bot: &tgbot.Bot{Token: "123:abc"}, Timeout: 1, OffsetStore: store}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- p.Run(ctx, d)
+	}()
+
+	// Wait until the poller asks for the updates after the album.
+	eventually(t, "the updates", func() bool { return queue.lastOffset() == 4 })
+
+	if offset, err := store.LoadOffset(); err != nil || offset != 0 {
+		t.Errorf("offset saved before the album was handled: %d, %v", offset, err)
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if albums, want := rec.get(), [][]int{{11, 12, 13}}; !reflect.DeepEqual(albums, want) {
+		t.Errorf("albums = %v, want %v", albums, want)
+	}
+
+	if offset, err := store.LoadOffset(); err != nil || offset != 4 {
+		t.Errorf("offset = %d, %v, want 4", offset, err)
+	}
+}
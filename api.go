@@ -7,22 +7,28 @@ package tgbot
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 )
 
 const (
-	ApiUrl = "https://api.telegram.org/bot%s/%s"
+	ApiUrl  = "https://api.telegram.org/bot%s/%s"
+	FileUrl = "https://api.telegram.org/file/bot%s/%s"
 )
 
 func (bot *Bot) makeRequest(method string, payload interface{}) ([]byte, error) {
-	url := fmt.Sprintf(ApiUrl, bot.Token, method)
+	return bot.makeRequestContext(context.Background(), method, payload)
+}
+
+func (bot *Bot) makeRequestContext(ctx context.Context, method string, payload interface{}) ([]byte, error) {
+	url := bot.methodUrl(method)
 
 	var b bytes.Buffer
 
@@ -30,17 +36,16 @@ func (bot *Bot) makeRequest(method string, payload interface{}) ([]byte, error)
 		return []byte{}, err
 	}
 
-	resp, err := http.Post(url, "application/json", &b)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, &b)
 
 	if err != nil {
-		return []byte{}, err
+		return []byte{}, bot.redactError(err)
 	}
 
-	resp.Close = true
-
-	defer resp.Body.Close()
+	req.Header.Set("Content-Type", "application/json")
 
-	respBytes, err := ioutil.ReadAll(resp.Body)
+	params, _ := payload.(map[string]string)
+	_, respBytes, err := bot.do(req, method, params)
 
 	if err != nil {
 		return []byte{}, err
@@ -49,7 +54,8 @@ func (bot *Bot) makeRequest(method string, payload interface{}) ([]byte, error)
 	return respBytes, nil
 }
 
-func (bot *Bot) makeFileRequest(method, token, name, path string, params map[string]string) ([]byte, error) {
+func (bot *Bot) makeFileRequest(ctx context.Context, method, name, path string,
+	params map[string]string) ([]byte, error) {
 	file, err := os.Open(path)
 
 	if err != nil {
@@ -78,33 +84,72 @@ func (bot *Bot) makeFileRequest(method, token, name, path string, params map[str
 		return []byte{}, err
 	}
 
-	url := fmt.Sprintf(ApiUrl, token, method)
-	req, err := http.NewRequest("POST", url, body)
+	url := bot.methodUrl(method)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 
 	if err != nil {
-		return []byte{}, err
+		return []byte{}, bot.redactError(err)
 	}
 
 	req.Header.Add("Content-Type", writer.FormDataContentType())
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	logParams := map[string]string{name: "@" + filepath.Base(path)}
+
+	for field, value := range params {
+		logParams[field] = value
+	}
+
+	resp, respBytes, err := bot.do(req, method, logParams)
 
 	if err != nil {
 		return []byte{}, err
 	}
 
 	if resp.StatusCode == http.StatusInternalServerError {
-		return []byte{}, fmt.Errorf("internal server error")
+		return []byte{}, bot.redactError(fmt.Errorf("tgbot: %s failed: %s", method, resp.Status))
 	}
 
-	respBytes, err := ioutil.ReadAll(resp.Body)
+	return respBytes, nil
+}
 
-	if err != nil {
-		return []byte{}, err
+func (bot *Bot) makeInputFileRequest(method, name string, file InputFile, params map[string]string) ([]byte, error) {
+	return bot.makeInputFileRequestContext(context.Background(), method, name, file, params)
+}
+
+// makeInputFileRequestContext uploads the file with multipart/form-data when it is on disk,
+// otherwise the file is passed by its file_id or URL.
+func (bot *Bot) makeInputFileRequestContext(ctx context.Context, method, name string, file InputFile,
+	params map[string]string) ([]byte, error) {
+	if file.IsOnDisk() {
+		return bot.makeFileRequest(ctx, method, name, file.FilePath, params)
 	}
 
-	return respBytes, nil
+	params[name] = file.value()
+
+	return bot.makeRequestContext(ctx, method, params)
+}
+
+// apiResponse is the envelope of every Bot API response.
+type apiResponse struct {
+	Ok          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+}
+
+// decodeResponse unmarshals the result of a successful response into result.
+func decodeResponse(jsonResp []byte, result interface{}) error {
+	var resp apiResponse
+
+	if err := json.Unmarshal(jsonResp, &resp); err != nil {
+		return err
+	}
+
+	if !resp.Ok {
+		return fmt.Errorf("tgbot: %s", resp.Description)
+	}
+
+	return json.Unmarshal(resp.Result, result)
 }
 
 // GetMe is a simple method for testing your bot's auth token. Requires no parameters.
@@ -133,6 +178,35 @@ func (bot *Bot) GetMe() (*User, error) {
 	return nil, fmt.Errorf("tgbot: %s", resp.Description)
 }
 
+// GetUpdates is to receive incoming updates using long polling. An array of Update objects is returned.
+func (bot *Bot) GetUpdates(opts *GetUpdatesOptions) ([]Update, error) {
+	return bot.getUpdates(context.Background(), opts)
+}
+
+func (bot *Bot) getUpdates(ctx context.Context, opts *GetUpdatesOptions) ([]Update, error) {
+	params := map[string]string{}
+
+	if opts != nil {
+		if err := opts.addOptions(params); err != nil {
+			return nil, err
+		}
+	}
+
+	jsonResp, err := bot.makeRequestContext(ctx, "getUpdates", params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []Update
+
+	if err = decodeResponse(jsonResp, &updates); err != nil {
+		return nil, err
+	}
+
+	return updates, nil
+}
+
 // SendMessage is to send text messages. On success, the sent Message is returned.
 func (bot *Bot) SendMessage(chatId, text string, opts *SendMessageOptions) (*Message, error) {
 	params := map[string]string{
@@ -141,7 +215,9 @@ func (bot *Bot) SendMessage(chatId, text string, opts *SendMessageOptions) (*Mes
 	}
 
 	if opts != nil {
-		opts.addOptions(params)
+		if err := opts.addOptions(params); err != nil {
+			return nil, err
+		}
 	}
 
 	jsonResp, err := bot.makeRequest("sendMessage", params)
@@ -167,4 +243,600 @@ func (bot *Bot) SendMessage(chatId, text string, opts *SendMessageOptions) (*Mes
 	return resp.Result, nil
 }
 
-//func (bot *Bot) SendPhoto(chatID string, photo InputFile)
+// SendSticker is to send static .WEBP or animated .TGS stickers. On success, the sent Message is returned.
+func (bot *Bot) SendSticker(chatId string, sticker InputFile, opts *SendStickerOptions) (*Message, error) {
+	params := map[string]string{
+		"chat_id": chatId,
+	}
+
+	if opts != nil {
+		opts.addOptions(params)
+	}
+
+	jsonResp, err := bot.makeInputFileRequest("sendSticker", "sticker", sticker, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var msg Message
+
+	if err = decodeResponse(jsonResp, &msg); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+// GetStickerSet is to get a sticker set. On success, a StickerSet object is returned.
+func (bot *Bot) GetStickerSet(name string) (*StickerSet, error) {
+	params := map[string]string{
+		"name": name,
+	}
+
+	jsonResp, err := bot.makeRequest("getStickerSet", params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var set StickerSet
+
+	if err = decodeResponse(jsonResp, &set); err != nil {
+		return nil, err
+	}
+
+	return &set, nil
+}
+
+// UploadStickerFile is to upload a .PNG file with a sticker for later use in CreateNewStickerSet
+// and AddStickerToSet methods (can be used multiple times). Returns the uploaded File on success.
+func (bot *Bot) UploadStickerFile(userId int, pngSticker InputFile) (*File, error) {
+	if !pngSticker.IsOnDisk() {
+		return nil, fmt.Errorf("tgbot: sticker file must be uploaded from disk")
+	}
+
+	params := map[string]string{
+		"user_id": strconv.Itoa(userId),
+	}
+
+	jsonResp, err := bot.makeInputFileRequest("uploadStickerFile", "png_sticker", pngSticker, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var file File
+
+	if err = decodeResponse(jsonResp, &file); err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// CreateNewStickerSet is to create a new sticker set owned by a user. The bot will be able to edit
+// the sticker set thus created. The set is animated if the sticker is a .TGS animation.
+// Returns True on success.
+func (bot *Bot) CreateNewStickerSet(userId int, name, title string, sticker InputSticker, containsMasks bool) (bool, error) {
+	params := map[string]string{
+		"user_id": strconv.Itoa(userId),
+		"name":    name,
+		"title":   title,
+	}
+
+	if containsMasks {
+		params["contains_masks"] = "true"
+	}
+
+	return bot.uploadSticker("createNewStickerSet", sticker, params)
+}
+
+// AddStickerToSet is to add a new sticker to a set created by the bot. Animated stickers can be added
+// to animated sticker sets and only to them. Animated sticker sets can have up to 50 stickers.
+// Static sticker sets can have up to 120 stickers. Returns True on success.
+func (bot *Bot) AddStickerToSet(userId int, name string, sticker InputSticker) (bool, error) {
+	params := map[string]string{
+		"user_id": strconv.Itoa(userId),
+		"name":    name,
+	}
+
+	return bot.uploadSticker("addStickerToSet", sticker, params)
+}
+
+func (bot *Bot) uploadSticker(method string, sticker InputSticker, params map[string]string) (bool, error) {
+	field, file, err := sticker.file()
+
+	if err != nil {
+		return false, err
+	}
+
+	params["emojis"] = sticker.Emojis
+
+	if sticker.MaskPosition != nil {
+		mask, err := json.Marshal(sticker.MaskPosition)
+
+		if err != nil {
+			return false, err
+		}
+
+		params["mask_position"] = string(mask)
+	}
+
+	jsonResp, err := bot.makeInputFileRequest(method, field, file, params)
+
+	if err != nil {
+		return false, err
+	}
+
+	var ok bool
+
+	if err = decodeResponse(jsonResp, &ok); err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// SetStickerPositionInSet is to move a sticker in a set created by the bot to a specific position.
+// Returns True on success.
+func (bot *Bot) SetStickerPositionInSet(sticker string, position int) (bool, error) {
+	params := map[string]string{
+		"sticker":  sticker,
+		"position": strconv.Itoa(position),
+	}
+
+	jsonResp, err := bot.makeRequest("setStickerPositionInSet", params)
+
+	if err != nil {
+		return false, err
+	}
+
+	var ok bool
+
+	if err = decodeResponse(jsonResp, &ok); err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// DeleteStickerFromSet is to delete a sticker from a set created by the bot. Returns True on success.
+func (bot *Bot) DeleteStickerFromSet(sticker string) (bool, error) {
+	params := map[string]string{
+		"sticker": sticker,
+	}
+
+	jsonResp, err := bot.makeRequest("deleteStickerFromSet", params)
+
+	if err != nil {
+		return false, err
+	}
+
+	var ok bool
+
+	if err = decodeResponse(jsonResp, &ok); err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// SetStickerSetThumb is to set the thumbnail of a sticker set. Animated thumbnails can be set
+// for animated sticker sets only. An empty thumb removes the thumbnail. Returns True on success.
+func (bot *Bot) SetStickerSetThumb(name string, userId int, thumb InputFile) (bool, error) {
+	params := map[string]string{
+		"name":    name,
+		"user_id": strconv.Itoa(userId),
+	}
+
+	var jsonResp []byte
+	var err error
+
+	if thumb.IsEmpty() {
+		jsonResp, err = bot.makeRequest("setStickerSetThumb", params)
+	} else {
+		jsonResp, err = bot.makeInputFileRequest("setStickerSetThumb", "thumb", thumb, params)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	var ok bool
+
+	if err = decodeResponse(jsonResp, &ok); err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// SendInvoice is to send invoices. Prices is a breakdown of the price, e.g. product price, tax, discount,
+// delivery cost, delivery tax, bonus, etc. The total is checked against the currency limits before
+// the invoice is sent. On success, the sent Message is returned.
+func (bot *Bot) SendInvoice(chatId, title, description, payload, providerToken, startParameter, currency string,
+	prices []LabeledPrice, opts *SendInvoiceOptions) (*Message, error) {
+	if err := ValidateInvoice(currency, prices); err != nil {
+		return nil, err
+	}
+
+	pricesJson, err := json.Marshal(prices)
+
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		"chat_id":         chatId,
+		"title":           title,
+		"description":     description,
+		"payload":         payload,
+		"provider_token":  providerToken,
+		"start_parameter": startParameter,
+		"currency":        currency,
+		"prices":          string(pricesJson),
+	}
+
+	if opts != nil {
+		opts.addOptions(params)
+	}
+
+	jsonResp, err := bot.makeRequest("sendInvoice", params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var msg Message
+
+	if err = decodeResponse(jsonResp, &msg); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+// AnswerShippingQuery is to reply to shipping queries sent for invoices with a flexible price.
+// If ok is false, errorMessage explains why it is impossible to complete the order and shippingOptions
+// are ignored. Returns True on success.
+func (bot *Bot) AnswerShippingQuery(shippingQueryId string, ok bool, shippingOptions []ShippingOption,
+	errorMessage string) (bool, error) {
+	params := map[string]string{
+		"shipping_query_id": shippingQueryId,
+		"ok":                strconv.FormatBool(ok),
+	}
+
+	if ok {
+		options, err := json.Marshal(shippingOptions)
+
+		if err != nil {
+			return false, err
+		}
+
+		params["shipping_options"] = string(options)
+	} else {
+		params["error_message"] = errorMessage
+	}
+
+	jsonResp, err := bot.makeRequest("answerShippingQuery", params)
+
+	if err != nil {
+		return false, err
+	}
+
+	var result bool
+
+	if err = decodeResponse(jsonResp, &result); err != nil {
+		return false, err
+	}
+
+	return result, nil
+}
+
+// AnswerPreCheckoutQuery is to respond to pre-checkout queries. The answer must be sent
+// within 10 seconds after the pre-checkout query was sent. If ok is false, errorMessage explains
+// the reason for failure to proceed with the checkout. Returns True on success.
+func (bot *Bot) AnswerPreCheckoutQuery(preCheckoutQueryId string, ok bool, errorMessage string) (bool, error) {
+	return bot.answerPreCheckoutQuery(context.Background(), preCheckoutQueryId, ok, errorMessage)
+}
+
+func (bot *Bot) answerPreCheckoutQuery(ctx context.Context, preCheckoutQueryId string, ok bool,
+	errorMessage string) (bool, error) {
+	params := map[string]string{
+		"pre_checkout_query_id": preCheckoutQueryId,
+		"ok":                    strconv.FormatBool(ok),
+	}
+
+	if !ok {
+		params["error_message"] = errorMessage
+	}
+
+	jsonResp, err := bot.makeRequestContext(ctx, "answerPreCheckoutQuery", params)
+
+	if err != nil {
+		return false, err
+	}
+
+	var result bool
+
+	if err = decodeResponse(jsonResp, &result); err != nil {
+		return false, err
+	}
+
+	return result, nil
+}
+
+// SendGame is to send a game. On success, the sent Message is returned.
+func (bot *Bot) SendGame(chatId, gameShortName string, opts *SendGameOptions) (*Message, error) {
+	params := map[string]string{
+		"chat_id":         chatId,
+		"game_short_name": gameShortName,
+	}
+
+	if opts != nil {
+		opts.addOptions(params)
+	}
+
+	jsonResp, err := bot.makeRequest("sendGame", params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var msg Message
+
+	if err = decodeResponse(jsonResp, &msg); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+// SetGameScore is to set the score of the specified user in a game. On success, if the message was sent
+// by the bot, returns the edited Message, otherwise (for inline messages) returns nil.
+// Returns an error, if the new score is not greater than the user's current score in the chat
+// and Force is false.
+func (bot *Bot) SetGameScore(userId, score int, target MessageTarget, opts *SetGameScoreOptions) (*Message, error) {
+	params := map[string]string{
+		"user_id": strconv.Itoa(userId),
+		"score":   strconv.Itoa(score),
+	}
+
+	if err := target.addOptions(params); err != nil {
+		return nil, err
+	}
+
+	if opts != nil {
+		opts.addOptions(params)
+	}
+
+	jsonResp, err := bot.makeRequest("setGameScore", params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result json.RawMessage
+
+	if err = decodeResponse(jsonResp, &result); err != nil {
+		return nil, err
+	}
+
+	if target.IsInline() {
+		return nil, nil
+	}
+
+	var msg Message
+
+	if err = json.Unmarshal(result, &msg); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+// GetGameHighScores is to get data for high score tables. Will return the score of the specified user
+// and several of their neighbors in a game.
+func (bot *Bot) GetGameHighScores(userId int, target MessageTarget) ([]GameHighScore, error) {
+	params := map[string]string{
+		"user_id": strconv.Itoa(userId),
+	}
+
+	if err := target.addOptions(params); err != nil {
+		return nil, err
+	}
+
+	jsonResp, err := bot.makeRequest("getGameHighScores", params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var scores []GameHighScore
+
+	if err = decodeResponse(jsonResp, &scores); err != nil {
+		return nil, err
+	}
+
+	return scores, nil
+}
+
+// AnswerCallbackQuery is to send answers to callback queries sent from inline keyboards. The answer
+// will be displayed to the user as a notification at the top of the chat screen or as an alert.
+// On success, True is returned.
+func (bot *Bot) AnswerCallbackQuery(callbackQueryId string, opts *AnswerCallbackQueryOptions) (bool, error) {
+	params := map[string]string{
+		"callback_query_id": callbackQueryId,
+	}
+
+	if opts != nil {
+		opts.addOptions(params)
+	}
+
+	jsonResp, err := bot.makeRequest("answerCallbackQuery", params)
+
+	if err != nil {
+		return false, err
+	}
+
+	var ok bool
+
+	if err = decodeResponse(jsonResp, &ok); err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// AnswerGameCallbackQuery answers a callback query of a callback_game button with the URL that opens
+// the game. On success, True is returned.
+func (bot *Bot) AnswerGameCallbackQuery(query *CallbackQuery, gameUrl string) (bool, error) {
+	if !query.IsGame() {
+		return false, fmt.Errorf("tgbot: callback query %s is not a game query", query.Id)
+	}
+
+	return bot.AnswerCallbackQuery(query.Id, &AnswerCallbackQueryOptions{Url: gameUrl})
+}
+
+// GetFile is to get basic info about a file and prepare it for downloading. For the moment, bots can download
+// files of up to 20MB in size. The file can then be downloaded with DownloadFile.
+func (bot *Bot) GetFile(fileId string) (*File, error) {
+	params := map[string]string{
+		"file_id": fileId,
+	}
+
+	jsonResp, err := bot.makeRequest("getFile", params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var file File
+
+	if err = decodeResponse(jsonResp, &file); err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// DownloadFile downloads the contents of a file by the path returned by GetFile.
+func (bot *Bot) DownloadFile(filePath string) ([]byte, error) {
+	req, err := http.NewRequest("GET", bot.fileUrl(filePath), nil)
+
+	if err != nil {
+		return nil, bot.redactError(err)
+	}
+
+	resp, body, err := bot.do(req, "file/"+filePath, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tgbot: cannot download file: %s", resp.Status)
+	}
+
+	return body, nil
+}
+
+// SetPassportDataErrors informs a user that some of the Telegram Passport elements they provided contains
+// errors. The user will not be able to re-submit their Passport to you until the errors are fixed.
+// Returns True on success.
+func (bot *Bot) SetPassportDataErrors(userId int, errors []PassportElementError) (bool, error) {
+	errorsJson, err := marshalPassportErrors(errors)
+
+	if err != nil {
+		return false, err
+	}
+
+	params := map[string]string{
+		"user_id": strconv.Itoa(userId),
+		"errors":  errorsJson,
+	}
+
+	jsonResp, err := bot.makeRequest("setPassportDataErrors", params)
+
+	if err != nil {
+		return false, err
+	}
+
+	var ok bool
+
+	if err = decodeResponse(jsonResp, &ok); err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// SendPhoto is to send photos. On success, the sent Message is returned.
+func (bot *Bot) SendPhoto(chatId string, photo InputFile, opts *SendPhotoOptions) (*Message, error) {
+	params := map[string]string{
+		"chat_id": chatId,
+	}
+
+	if opts != nil {
+		if err := opts.addOptions(params); err != nil {
+			return nil, err
+		}
+	}
+
+	jsonResp, err := bot.makeInputFileRequest("sendPhoto", "photo", photo, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var msg Message
+
+	if err = decodeResponse(jsonResp, &msg); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+// EditMessageText is to edit text and game messages. On success, if the edited message was sent by the bot,
+// the edited Message is returned, otherwise (for inline messages) nil is returned.
+func (bot *Bot) EditMessageText(text string, target MessageTarget, opts *EditMessageTextOptions) (*Message, error) {
+	params := map[string]string{
+		"text": text,
+	}
+
+	if err := target.addOptions(params); err != nil {
+		return nil, err
+	}
+
+	if opts != nil {
+		if err := opts.addOptions(params); err != nil {
+			return nil, err
+		}
+	}
+
+	jsonResp, err := bot.makeRequest("editMessageText", params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result json.RawMessage
+
+	if err = decodeResponse(jsonResp, &result); err != nil {
+		return nil, err
+	}
+
+	if target.IsInline() {
+		return nil, nil
+	}
+
+	var msg Message
+
+	if err = json.Unmarshal(result, &msg); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
@@ -0,0 +1,198 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Command is a bot command parsed from the bot_command entity at the start of a message,
+// e.g. “/start@jobs_bot some args”.
+type Command struct {
+	// Command name without the leading slash and the bot username, e.g. “start”
+	Name string
+	// Username of the bot the command is addressed to, empty if the command has no mention
+	Mention string
+	// Text following the command, with leading whitespace removed
+	RawArgs string
+	// RawArgs split into arguments with shell-like quoting
+	Args []string
+}
+
+// ParseCommand parses the command that starts the text or the caption of the message.
+// Returns nil if the message does not start with a bot_command entity.
+func ParseCommand(msg *Message) *Command {
+	if cmd := parseCommand(msg.Text, msg.Entities); cmd != nil {
+		return cmd
+	}
+
+	return parseCommand(msg.Caption, msg.CaptionEntities)
+}
+
+func parseCommand(text string, entities *[]MessageEntity) *Command {
+	if entities == nil {
+		return nil
+	}
+
+	for _, e := range *entities {
+		if e.Type != EntityBotCommand || e.Offset != 0 {
+			continue
+		}
+
+		_, end := e.Range(text)
+		name := strings.TrimPrefix(text[:end], "/")
+		mention := ""
+
+		if i := strings.IndexByte(name, '@'); i >= 0 {
+			name, mention = name[:i], name[i+1:]
+		}
+
+		raw := strings.TrimLeftFunc(text[end:], unicode.IsSpace)
+
+		return &Command{
+			Name:    name,
+			Mention: mention,
+			RawArgs: raw,
+			Args:    SplitArgs(raw),
+		}
+	}
+
+	return nil
+}
+
+// IsFor reports whether the command is addressed to the bot with the username,
+// either explicitly or by having no mention.
+func (cmd *Command) IsFor(username string) bool {
+	return cmd.Mention == "" || strings.EqualFold(cmd.Mention, username)
+}
+
+// Is reports whether the command has one of the names. Names are compared case-insensitively.
+func (cmd *Command) Is(names ...string) bool {
+	for _, name := range names {
+		if strings.EqualFold(cmd.Name, strings.TrimPrefix(name, "/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SplitArgs splits s into arguments separated by whitespace. As in a shell, single quotes preserve
+// the quoted text literally, double quotes preserve it except for backslash escapes of a double quote
+// or a backslash, and a backslash outside of quotes escapes the next character.
+// An unterminated quote extends to the end of s.
+func SplitArgs(s string) []string {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+
+		switch {
+		case r == '\'':
+			inArg = true
+			end := strings.IndexByte(s[i:], '\'')
+
+			if end < 0 {
+				end = len(s) - i
+			}
+
+			arg.WriteString(s[i : i+end])
+			i += end
+
+			if i < len(s) {
+				i++
+			}
+		case r == '"':
+			inArg = true
+
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\') {
+					i++
+				}
+
+				arg.WriteByte(s[i])
+				i++
+			}
+
+			if i < len(s) {
+				i++
+			}
+		case r == '\\' && i < len(s):
+			inArg = true
+			next, nextSize := utf8.DecodeRuneInString(s[i:])
+			arg.WriteRune(next)
+			i += nextSize
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			inArg = true
+			arg.WriteRune(r)
+		}
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args
+}
+
+// Command returns the command of the update's message if it is addressed to the bot,
+// or nil if there is none.
+func (c *Context) Command() *Command {
+	if c.command != nil {
+		return c.command
+	}
+
+	msg := c.Message()
+
+	if msg == nil || c.Update.CallbackQuery != nil {
+		return nil
+	}
+
+	cmd := ParseCommand(msg)
+
+	if cmd == nil {
+		return nil
+	}
+
+	if c.Bot.Me != nil && !cmd.IsFor(c.Bot.Me.Username) {
+		return nil
+	}
+
+	c.command = cmd
+
+	return cmd
+}
+
+// Commands matches messages starting with one of the commands addressed to the bot.
+// Without names, it matches any command addressed to the bot.
+func Commands(names ...string) Filter {
+	return func(c *Context) bool {
+		cmd := c.Command()
+
+		if cmd == nil {
+			return false
+		}
+
+		return len(names) == 0 || cmd.Is(names...)
+	}
+}
+
+// OnCommand registers the handler for new messages with one of the commands. Commands addressed
+// to other bots are ignored. The parsed command is available through Context.Command.
+func (r *Router) OnCommand(h HandlerFunc, names ...string) *Route {
+	return r.Handle(UpdateMessage, h, Commands(names...))
+}
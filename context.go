@@ -0,0 +1,84 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Context carries an update and the bot that received it through handlers and filters.
+// It embeds the context.Context the update is processed with.
+type Context struct {
+	context.Context
+	Bot    *Bot
+	Update *Update
+
+	command *Command
+	conv    *Conversation
+	convKey ConversationKey
+	session *conversationSession
+	// dispatcher and job are set for updates processed by a dispatcher.
+	dispatcher *Dispatcher
+	job        *job
+}
+
+// NewContext returns a Context for processing the update received by the bot.
+func NewContext(ctx context.Context, bot *Bot, u *Update) *Context {
+	return &Context{
+		Context: ctx,
+		Bot:     bot,
+		Update:  u,
+	}
+}
+
+// Message returns the effective message of the update, or nil if there is none.
+func (c *Context) Message() *Message {
+	return c.Update.EffectiveMessage()
+}
+
+// Chat returns the effective chat of the update, or nil if there is none.
+func (c *Context) Chat() *Chat {
+	return c.Update.EffectiveChat()
+}
+
+// Sender returns the effective user of the update, or nil if there is none.
+func (c *Context) Sender() *User {
+	return c.Update.EffectiveUser()
+}
+
+// Text returns the text of the update: the text or caption of a message, the data of a callback query
+// or the query of an inline query.
+func (c *Context) Text() string {
+	switch {
+	case c.Update.CallbackQuery != nil:
+		return c.Update.CallbackQuery.Data
+	case c.Update.InlineQuery != nil:
+		return c.Update.InlineQuery.Query
+	}
+
+	if msg := c.Message(); msg != nil {
+		if msg.Text != "" {
+			return msg.Text
+		}
+
+		return msg.Caption
+	}
+
+	return ""
+}
+
+// Reply sends a text message to the chat of the update.
+func (c *Context) Reply(text string, opts *SendMessageOptions) (*Message, error) {
+	chat := c.Chat()
+
+	if chat == nil {
+		return nil, fmt.Errorf("tgbot: %s update has no chat to reply to", c.Update.Kind())
+	}
+
+	return c.Bot.SendMessage(strconv.FormatInt(chat.Id, 10), text, opts)
+}
@@ -0,0 +1,333 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// conversationGrace is the time a session is kept after its state expired, so that OnTimeout
+// can be called with the next update of the user.
+const conversationGrace = 24 * time.Hour
+
+// ErrNoConversation is returned by the state methods of a Context that is not processed
+// by a Conversation middleware or has neither a chat nor a user.
+var ErrNoConversation = errors.New("tgbot: update is not part of a conversation")
+
+// Conversation is a finite-state machine for dialogs. Each user in each chat has their own current state,
+// set by handlers with Context.SetState. While a state is active, updates of the user in the chat
+// are first passed to the handler bound to the state, and to the router only if that handler
+// returns ErrNotHandled or there is none.
+//
+// A conversation is attached to a router with its middleware:
+//
+//	cv := tgbot.NewConversation()
+//	cv.State("ask_name", askName)
+//	router.Use(cv.Middleware())
+type Conversation struct {
+	// Name distinguishes the sessions of conversations sharing a storage.
+	Name string
+	// SeparateBots keeps the sessions of each bot apart, for a conversation attached to a router shared
+	// by several bots. Changing it makes the conversation forget the sessions in its storage.
+	SeparateBots bool
+	// Storage keeps the states and data of the conversation, a MemoryStorage created by NewConversation
+	// by default. Use a persistent storage for conversations to survive restarts.
+	Storage Storage
+	// Timeout is the time a state stays active without updates from the user, unless the state
+	// has its own timeout. States never expire if zero.
+	Timeout time.Duration
+	// CancelCommands are commands that end the conversation in any state, e.g. “cancel”.
+	CancelCommands []string
+	// OnCancel is called after the conversation was ended by a cancel command.
+	OnCancel HandlerFunc
+	// OnTimeout is called with the first update after the state expired, before the update is routed.
+	// Context.State returns the expired state while it runs.
+	OnTimeout HandlerFunc
+
+	mu     sync.Mutex
+	states map[string]*ConversationState
+}
+
+// ConversationKey identifies the conversation of a user in a chat. ChatId is zero for updates without
+// a chat, such as inline queries, and UserId is zero for updates without a user, such as channel posts.
+// BotId identifies the bot for conversations with SeparateBots.
+type ConversationKey struct {
+	BotId  string
+	ChatId int64
+	UserId int
+}
+
+// ConversationState is a named state of a conversation.
+type ConversationState struct {
+	name    string
+	handler Handler
+	timeout time.Duration
+}
+
+// WithTimeout sets the time the state stays active without updates, overriding the conversation's Timeout.
+func (s *ConversationState) WithTimeout(d time.Duration) *ConversationState {
+	s.timeout = d
+
+	return s
+}
+
+type conversationSession struct {
+	State   string            `json:"state"`
+	Data    map[string]string `json:"data,omitempty"`
+	Expires time.Time         `json:"expires,omitempty"`
+}
+
+// NewConversation returns a conversation without states kept in memory.
+func NewConversation() *Conversation {
+	return &Conversation{
+		Storage: NewMemoryStorage(),
+		states:  make(map[string]*ConversationState),
+	}
+}
+
+// State binds the handler to the named state.
+func (cv *Conversation) State(name string, h HandlerFunc) *ConversationState {
+	s := &ConversationState{
+		name:    name,
+		handler: h,
+		timeout: -1,
+	}
+
+	cv.mu.Lock()
+	cv.states[name] = s
+	cv.mu.Unlock()
+
+	return s
+}
+
+// Middleware returns the middleware that passes the updates of users in an active state
+// to the state handlers and saves the state set by handlers.
+func (cv *Conversation) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(c *Context) error {
+			key, ok := conversationKey(c.Update)
+
+			if !ok {
+				return next.HandleUpdate(c)
+			}
+
+			if cv.SeparateBots {
+				key.BotId = botId(c.Bot)
+			}
+
+			session, err := cv.load(key)
+
+			if err != nil {
+				return err
+			}
+
+			c.conv = cv
+			c.convKey = key
+			c.session = session
+
+			if c.session != nil && !c.session.Expires.IsZero() && time.Now().After(c.session.Expires) {
+				if cv.OnTimeout != nil {
+					err = cv.OnTimeout(c)
+				}
+
+				c.session = nil
+
+				if err != nil && err != ErrNotHandled {
+					if saveErr := cv.save(c); saveErr != nil {
+						return saveErr
+					}
+
+					return err
+				}
+			}
+
+			err = cv.handle(c, next)
+
+			if saveErr := cv.save(c); saveErr != nil && err == nil {
+				err = saveErr
+			}
+
+			return err
+		})
+	}
+}
+
+func (cv *Conversation) handle(c *Context, next Handler) error {
+	if c.session == nil {
+		return next.HandleUpdate(c)
+	}
+
+	if cmd := c.Command(); cmd != nil && len(cv.CancelCommands) > 0 && cmd.Is(cv.CancelCommands...) {
+		c.session = nil
+
+		if cv.OnCancel != nil {
+			return cv.OnCancel(c)
+		}
+
+		return nil
+	}
+
+	cv.mu.Lock()
+	state := cv.states[c.session.State]
+	cv.mu.Unlock()
+
+	if state != nil {
+		if err := state.handler.HandleUpdate(c); err != ErrNotHandled {
+			return err
+		}
+	}
+
+	return next.HandleUpdate(c)
+}
+
+func (cv *Conversation) load(key ConversationKey) (*conversationSession, error) {
+	data, ok, err := cv.Storage.Get(cv.storageKey(key))
+
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var session conversationSession
+
+	if err = json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// save stores the session of the context, restarting the timeout of its state.
+func (cv *Conversation) save(c *Context) error {
+	key := cv.storageKey(c.convKey)
+
+	if c.session == nil {
+		return cv.Storage.Delete(key)
+	}
+
+	var ttl time.Duration
+	c.session.Expires = time.Time{}
+
+	if timeout := cv.timeout(c.session.State); timeout > 0 {
+		c.session.Expires = time.Now().Add(timeout)
+		ttl = timeout + conversationGrace
+	}
+
+	data, err := json.Marshal(c.session)
+
+	if err != nil {
+		return err
+	}
+
+	return cv.Storage.Set(key, data, ttl)
+}
+
+func (cv *Conversation) storageKey(key ConversationKey) string {
+	prefix := "conversation/" + cv.Name + "/"
+
+	if key.BotId != "" {
+		prefix += key.BotId + "/"
+	}
+
+	return prefix + strconv.FormatInt(key.ChatId, 10) + "/" + strconv.Itoa(key.UserId)
+}
+
+// timeout returns the time the named state stays active.
+func (cv *Conversation) timeout(name string) time.Duration {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	if s := cv.states[name]; s != nil && s.timeout >= 0 {
+		return s.timeout
+	}
+
+	return cv.Timeout
+}
+
+func conversationKey(u *Update) (ConversationKey, bool) {
+	var key ConversationKey
+
+	if chat := u.EffectiveChat(); chat != nil {
+		key.ChatId = chat.Id
+	}
+
+	if user := u.EffectiveUser(); user != nil {
+		key.UserId = user.Id
+	}
+
+	return key, key != ConversationKey{}
+}
+
+// State returns the current conversation state of the user in the chat, or an empty string
+// if no state is active.
+func (c *Context) State() string {
+	if c.session == nil {
+		return ""
+	}
+
+	return c.session.State
+}
+
+// SetState sets the conversation state of the user in the chat. The conversation data is kept.
+func (c *Context) SetState(state string) error {
+	if c.conv == nil {
+		return ErrNoConversation
+	}
+
+	c.conv.mu.Lock()
+	_, ok := c.conv.states[state]
+	c.conv.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("tgbot: unknown conversation state %q", state)
+	}
+
+	if c.session == nil {
+		c.session = &conversationSession{}
+	}
+
+	c.session.State = state
+
+	return nil
+}
+
+// EndConversation ends the conversation of the user in the chat and discards its data.
+func (c *Context) EndConversation() {
+	c.session = nil
+}
+
+// StateData returns the value stored in the conversation under the key.
+func (c *Context) StateData(key string) string {
+	if c.session == nil {
+		return ""
+	}
+
+	return c.session.Data[key]
+}
+
+// SetStateData stores the value in the conversation under the key. The conversation must have
+// an active state.
+func (c *Context) SetStateData(key, value string) error {
+	if c.conv == nil {
+		return ErrNoConversation
+	}
+
+	if c.session == nil {
+		return fmt.Errorf("tgbot: conversation has no active state")
+	}
+
+	if c.session.Data == nil {
+		c.session.Data = make(map[string]string)
+	}
+
+	c.session.Data[key] = value
+
+	return nil
+}
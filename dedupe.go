@@ -0,0 +1,145 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDedupeSize = 10000
+	// DefaultDedupeTTL is the time update identifiers are kept in the storage of a Deduplicator.
+	// Telegram keeps undelivered updates for 24 hours.
+	DefaultDedupeTTL = 48 * time.Hour
+)
+
+// Deduplicator remembers the identifiers of the updates passed to a dispatcher, so that updates
+// delivered twice, e.g. by webhook retries or after a restart, are dropped before handlers run.
+// Identifiers are kept in a bounded in-memory LRU and, optionally, in a storage shared with other
+// processes or kept across restarts.
+//
+// An update is remembered when it is dispatched, not when it is processed, so an update whose
+// processing was interrupted by a crash is not processed again. An update that could not be dispatched,
+// e.g. because the dispatcher was shut down, is forgotten, so that it is accepted when it is delivered again.
+type Deduplicator struct {
+	size    int
+	storage Storage
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	order *list.List
+	seen  map[dedupeKey]*list.Element
+}
+
+type dedupeKey struct {
+	bot      string
+	updateId int
+}
+
+// NewDeduplicator returns a deduplicator that remembers the last size update identifiers in memory
+// and, if storage is not nil, for ttl in the storage. Zero values select DefaultDedupeSize
+// and DefaultDedupeTTL.
+func NewDeduplicator(size int, storage Storage, ttl time.Duration) *Deduplicator {
+	if size <= 0 {
+		size = DefaultDedupeSize
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultDedupeTTL
+	}
+
+	return &Deduplicator{
+		size:    size,
+		storage: storage,
+		ttl:     ttl,
+		order:   list.New(),
+		seen:    make(map[dedupeKey]*list.Element),
+	}
+}
+
+// Seen remembers the update received by the bot and reports whether it was seen before.
+func (d *Deduplicator) Seen(bot *Bot, u *Update) (bool, error) {
+	key := dedupeKey{bot: botId(bot), updateId: u.UpdateId}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e, ok := d.seen[key]; ok {
+		d.order.MoveToFront(e)
+
+		return true, nil
+	}
+
+	if d.storage != nil {
+		claimed, err := d.storage.CompareAndSwap("dedupe/"+key.bot+"/"+strconv.Itoa(key.updateId), nil, []byte{1}, d.ttl)
+
+		if err != nil {
+			return false, err
+		}
+
+		if !claimed {
+			d.remember(key)
+
+			return true, nil
+		}
+	}
+
+	d.remember(key)
+
+	return false, nil
+}
+
+// Forget makes the deduplicator forget the update received by the bot, so that it is not reported as seen
+// when it is delivered again.
+func (d *Deduplicator) Forget(bot *Bot, u *Update) error {
+	key := dedupeKey{bot: botId(bot), updateId: u.UpdateId}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e, ok := d.seen[key]; ok {
+		d.order.Remove(e)
+		delete(d.seen, key)
+	}
+
+	if d.storage != nil {
+		return d.storage.Delete("dedupe/" + key.bot + "/" + strconv.Itoa(key.updateId))
+	}
+
+	return nil
+}
+
+func (d *Deduplicator) remember(key dedupeKey) {
+	d.seen[key] = d.order.PushFront(key)
+
+	if d.order.Len() > d.size {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.seen, oldest.Value.(dedupeKey))
+	}
+}
+
+// botId returns a stable identifier of the bot: its user identifier, which is also the part
+// of the token before the colon.
+func botId(bot *Bot) string {
+	if bot.Me != nil {
+		return strconv.Itoa(bot.Me.Id)
+	}
+
+	if i := strings.IndexByte(bot.Token, ':'); i >= 0 {
+		return bot.Token[:i]
+	}
+
+	sum := sha256.Sum256([]byte(bot.Token))
+
+	return hex.EncodeToString(sum[:8])
+}
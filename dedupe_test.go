@@ -0,0 +1,194 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+func countingHandler(n *int32) tgbot.Handler {
+	return tgbot.HandlerFunc(func(c *tgbot.Context) error {
+		atomic.AddInt32(n, 1)
+
+		return nil
+	})
+}
+
+// undeletableStorage is a storage that fails to delete values.
+type undeletableStorage struct {
+	*tgbot.MemoryStorage
+}
+
+func (s undeletableStorage) Delete(key string) error {
+	return errors.New("storage is read-only")
+}
+
+func TestDeduplicatorDropsDuplicates(t *testing.T) {
+	var n int32
+	bot := &tgbot.Bot{Token: "123:abc"}
+	d := tgbot.NewDispatcher(countingHandler(&n), 1, 4)
+	d.Deduplicator = tgbot.NewDeduplicator(0, tgbot.NewMemoryStorage(), 0)
+
+	for i := 0; i < 3; i++ {
+		if err := d.Dispatch(context.Background(), bot, &tgbot.Update{UpdateId: 7}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+}
+
+func TestDeduplicatorSharesStorage(t *testing.T) {
+	var n int32
+	storage := tgbot.NewMemoryStorage()
+	bot := &tgbot.Bot{Token: "123:abc"}
+
+	// Two processes sharing the storage receive the update.
+	for i := 0; i < 2; i++ {
+		d := tgbot.NewDispatcher(countingHandler(&n), 1, 1)
+		d.Deduplicator = tgbot.NewDeduplicator(0, storage, 0)
+
+		if err := d.Dispatch(context.Background(), bot, &tgbot.Update{UpdateId: 7}); err != nil {
+			t.Fatal(err)
+		}
+
+		d.Shutdown(context.Background())
+	}
+
+	if n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+}
+
+func TestDeduplicatorForgetsUpdatesNotDispatched(t *testing.T) {
+	var n int32
+	bot := &tgbot.Bot{Token: "123:abc"}
+	storage := tgbot.NewMemoryStorage()
+
+	closed := tgbot.NewDispatcher(countingHandler(&n), 1, 1)
+	closed.Deduplicator = tgbot.NewDeduplicator(0, storage, 0)
+	closed.Shutdown(context.Background())
+
+	if err := closed.Dispatch(context.Background(), bot, &tgbot.Update{UpdateId: 7}); err != tgbot.ErrDispatcherClosed {
+		t.Fatalf("Dispatch after Shutdown = %v, want ErrDispatcherClosed", err)
+	}
+
+	// A new process sharing the storage receives the redelivered update.
+	d := tgbot.NewDispatcher(countingHandler(&n), 1, 1)
+	d.Deduplicator = tgbot.NewDeduplicator(0, storage, 0)
+
+	if err := d.Dispatch(context.Background(), bot, &tgbot.Update{UpdateId: 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Shutdown(context.Background())
+
+	if n != 1 {
+		t.Errorf("redelivered update ran %d times, want 1", n)
+	}
+}
+
+func TestDeduplicatorForgetsUpdatesCancelledByBackpressure(t *testing.T) {
+	var n int32
+	release := make(chan struct{})
+	bot := &tgbot.Bot{Token: "123:abc"}
+	d := tgbot.NewDispatcher(tgbot.HandlerFunc(func(c *tgbot.Context) error {
+		if c.Update.UpdateId == 1 {
+			<-release
+		}
+
+		atomic.AddInt32(&n, 1)
+
+		return nil
+	}), 1, 1)
+	d.Deduplicator = tgbot.NewDeduplicator(0, tgbot.NewMemoryStorage(), 0)
+
+	if err := d.Dispatch(context.Background(), bot, &tgbot.Update{UpdateId: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The queue is full: the dispatch of the second update is abandoned.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := d.Dispatch(ctx, bot, &tgbot.Update{UpdateId: 2}); err != context.Canceled {
+		t.Fatalf("Dispatch with a full queue = %v, want context.Canceled", err)
+	}
+
+	close(release)
+
+	if err := d.Dispatch(context.Background(), bot, &tgbot.Update{UpdateId: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Shutdown(context.Background())
+
+	if got := atomic.LoadInt32(&n); got != 2 {
+		t.Errorf("handler ran %d times, want 2", got)
+	}
+}
+
+func TestDeduplicatorReportsUpdatesItCannotForget(t *testing.T) {
+	var reported []error
+	bot := &tgbot.Bot{Token: "123:abc"}
+	d := tgbot.NewDispatcher(countingHandler(new(int32)), 1, 1)
+	d.Deduplicator = tgbot.NewDeduplicator(0, undeletableStorage{tgbot.NewMemoryStorage()}, 0)
+	d.OnError = func(c *tgbot.Context, err error) {
+		if c.Update.UpdateId != 7 {
+			t.Errorf("error reported for update %d", c.Update.UpdateId)
+		}
+
+		reported = append(reported, err)
+	}
+	d.Shutdown(context.Background())
+
+	if err := d.Dispatch(context.Background(), bot, &tgbot.Update{UpdateId: 7}); err != tgbot.ErrDispatcherClosed {
+		t.Fatalf("Dispatch after Shutdown = %v, want ErrDispatcherClosed", err)
+	}
+
+	if len(reported) != 1 || !strings.Contains(reported[0].Error(), "read-only") {
+		t.Errorf("reported errors %v, want the error of the storage", reported)
+	}
+}
+
+func TestPollerSavesOffsetOfDuplicates(t *testing.T) {
+	store := tgbot.NewStorageOffsetStore(tgbot.NewMemoryStorage(), "offset")
+	pt := newPollerTest(t, store)
+	pt.d.Deduplicator = tgbot.NewDeduplicator(0, tgbot.NewMemoryStorage(), 0)
+
+	// Another process handled the updates, but did not save the offset after them.
+	for id := 1; id <= 2; id++ {
+		pt.queue.add(id, 10)
+
+		if _, err := pt.d.Deduplicator.Seen(pt.poller.Bot, &tgbot.Update{UpdateId: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pt.run()
+	eventually(t, "the offset after the duplicates", func() bool { return savedOffset(t, store) == 3 })
+	pt.cancel()
+
+	if err := <-pt.done; err != nil {
+		t.Fatal(err)
+	}
+
+	if handled := pt.handledIds(); len(handled) != 0 {
+		t.Errorf("duplicates %v were handled", handled)
+	}
+}
@@ -0,0 +1,436 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWorkers   = 8
+	DefaultQueueSize = 256
+)
+
+// ErrDispatcherClosed is returned by Dispatch after the dispatcher was shut down.
+var ErrDispatcherClosed = errors.New("tgbot: dispatcher is shut down")
+
+// Dispatcher runs a handler for updates on a pool of workers. Updates of the same chat, or of the same user
+// for updates that have no chat such as inline queries, are processed sequentially in the order they were
+// dispatched, while updates of different chats are processed in parallel. Updates that belong to neither
+// are processed in any order.
+//
+// The number of updates waiting to be processed is bounded: Dispatch blocks while the queue is full,
+// which slows down the poller or the webhook feeding the dispatcher.
+type Dispatcher struct {
+	// Handler processes the updates. Panics in the handler are recovered and reported as *PanicError.
+	Handler Handler
+	// OnError is called with the errors returned by the handler, except ErrNotHandled and ErrForbidden,
+	// and with the errors of the Deduplicator forgetting an update that could not be dispatched.
+	// If nil, the errors are logged with the standard logger.
+	OnError func(c *Context, err error)
+	// Deduplicator drops updates that were dispatched before. Optional.
+	Deduplicator *Deduplicator
+	// Instrumentation observes the handler runs, e.g. to collect Metrics. Optional.
+	Instrumentation Instrumentation
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	slots    chan struct{}
+	runnable chan laneKey
+	done     chan struct{}
+	mu       sync.Mutex
+	lanes    map[laneKey]*lane
+	closed   bool
+	abandon  bool
+	wg       sync.WaitGroup
+	flushers map[flusher]bool
+}
+
+// laneKey identifies a sequence of updates that must be processed in order.
+type laneKey struct {
+	bot       *Bot
+	id        int64
+	unordered bool
+}
+
+type lane struct {
+	pending []*job
+}
+
+type job struct {
+	bot    *Bot
+	update *Update
+	// done is called after the update was processed, unless it was dropped by Shutdown.
+	done func()
+	// finish is called when the job completes, with whether its update was processed.
+	finish func(processed bool)
+
+	mu sync.Mutex
+	// holds counts what the job waits for to complete: the processing of its update, and the updates
+	// held back by middlewares until they are processed with others, such as the messages of an album.
+	holds   int
+	dropped bool
+}
+
+// flusher holds back updates processed by a dispatcher, such as a MediaGroupCollector.
+type flusher interface {
+	// Flush passes the updates held back to the handler.
+	Flush()
+}
+
+// NewDispatcher returns a dispatcher that runs h on workers goroutines and holds at most queueSize
+// updates waiting to be processed. Zero values select DefaultWorkers and DefaultQueueSize.
+func NewDispatcher(h Handler, workers, queueSize int) *Dispatcher {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		Handler:  h,
+		ctx:      ctx,
+		cancel:   cancel,
+		slots:    make(chan struct{}, queueSize),
+		runnable: make(chan laneKey, queueSize),
+		done:     make(chan struct{}),
+		lanes:    make(map[laneKey]*lane),
+	}
+
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+
+	return d
+}
+
+// Dispatch queues the update received by the bot for processing. It blocks while the queue is full
+// until ctx is done. The update is processed with a context that is not derived from ctx.
+// Duplicate updates are dropped without an error if the dispatcher has a Deduplicator.
+func (d *Dispatcher) Dispatch(ctx context.Context, bot *Bot, u *Update) error {
+	return d.dispatch(ctx, bot, u, nil)
+}
+
+// DispatchSync queues the update like Dispatch and waits until it is processed or ctx is done.
+// A message held back by a MediaGroupCollector is processed when its album is.
+// It is meant for tests that check the effects of handling an update.
+func (d *Dispatcher) DispatchSync(ctx context.Context, bot *Bot, u *Update) error {
+	processed := make(chan struct{})
+
+	if err := d.dispatch(ctx, bot, u, func() { close(processed) }); err != nil {
+		return err
+	}
+
+	select {
+	case <-processed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, bot *Bot, u *Update, done func()) error {
+	if d.Deduplicator != nil {
+		seen, err := d.Deduplicator.Seen(bot, u)
+
+		if err != nil {
+			return err
+		}
+
+		if seen {
+			if done != nil {
+				done()
+			}
+
+			return nil
+		}
+	}
+
+	err := d.enqueue(ctx, &job{bot: bot, update: u, done: done, holds: 1}, false)
+
+	if err != nil && d.Deduplicator != nil {
+		// The update was claimed but will not be processed: let its redelivery through.
+		if fErr := d.Deduplicator.Forget(bot, u); fErr != nil {
+			d.reportError(NewContext(d.ctx, bot, u),
+				fmt.Errorf("tgbot: update %d was not dispatched and will be dropped if delivered again: %v",
+					u.UpdateId, fErr))
+		}
+	}
+
+	return err
+}
+
+// redispatch queues an update made from updates the dispatcher processed, such as an album, even while
+// the dispatcher is shutting down. finish is called when the update was processed or dropped.
+func (d *Dispatcher) redispatch(bot *Bot, u *Update, finish func(processed bool)) {
+	if err := d.enqueue(d.ctx, &job{bot: bot, update: u, finish: finish, holds: 1}, true); err != nil {
+		finish(false)
+	}
+}
+
+// enqueue adds the job to the lane of its chat once there is room in the queue. Internal jobs are
+// accepted after Shutdown was called; they are queued while jobs holding them keep the workers running.
+func (d *Dispatcher) enqueue(ctx context.Context, j *job, internal bool) error {
+	done := d.done
+
+	if internal {
+		done = nil
+	}
+
+	select {
+	case d.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return ErrDispatcherClosed
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed && !internal {
+		<-d.slots
+
+		return ErrDispatcherClosed
+	}
+
+	key := updateKey(j.bot, j.update)
+	l, ok := d.lanes[key]
+
+	if !ok {
+		l = &lane{}
+		d.lanes[key] = l
+	}
+
+	l.pending = append(l.pending, j)
+	d.wg.Add(1)
+
+	if !ok {
+		d.runnable <- key
+	}
+
+	return nil
+}
+
+// hold keeps the job from completing until the returned function is called with whether the update
+// of the job was eventually processed. The function can be called more than once.
+func (d *Dispatcher) hold(j *job) func(processed bool) {
+	j.mu.Lock()
+	j.holds++
+	j.mu.Unlock()
+
+	var once sync.Once
+
+	return func(processed bool) {
+		once.Do(func() {
+			d.complete(j, processed)
+		})
+	}
+}
+
+// complete releases a hold of the job. When the last hold is released, the job is finished and,
+// unless it was dropped, done.
+func (d *Dispatcher) complete(j *job, processed bool) {
+	j.mu.Lock()
+	j.holds--
+
+	if !processed {
+		j.dropped = true
+	}
+
+	last, dropped := j.holds == 0, j.dropped
+	j.mu.Unlock()
+
+	if !last {
+		return
+	}
+
+	if j.finish != nil {
+		j.finish(!dropped)
+	}
+
+	if !dropped && j.done != nil {
+		j.done()
+	}
+
+	d.wg.Done()
+}
+
+// register makes Flush flush f.
+func (d *Dispatcher) register(f flusher) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.flushers == nil {
+		d.flushers = make(map[flusher]bool)
+	}
+
+	d.flushers[f] = true
+}
+
+// Flush passes the updates held back by middlewares, such as the media groups gathered
+// by a MediaGroupCollector, to the handler without waiting. Shutdown flushes the dispatcher
+// before it waits for the queued updates.
+func (d *Dispatcher) Flush() {
+	d.mu.Lock()
+	flushers := make([]flusher, 0, len(d.flushers))
+
+	for f := range d.flushers {
+		flushers = append(flushers, f)
+	}
+
+	d.mu.Unlock()
+
+	for _, f := range flushers {
+		f.Flush()
+	}
+}
+
+// Shutdown stops accepting updates and waits until the queued updates are processed or ctx is done.
+// The dispatcher is flushed whenever no update is queued, so that updates held back by middlewares
+// are processed too. If ctx is done first, the context of the handlers still running is cancelled,
+// the updates that are still queued or held back are dropped, and ctx.Err() is returned.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.Flush()
+	d.mu.Lock()
+
+	if !d.closed {
+		d.closed = true
+		close(d.done)
+
+		go func() {
+			d.wg.Wait()
+			close(d.runnable)
+			d.cancel()
+		}()
+	}
+
+	d.mu.Unlock()
+
+	drained := make(chan struct{})
+
+	go func() {
+		d.wg.Wait()
+		close(drained)
+	}()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-drained:
+			return nil
+		case <-ticker.C:
+			d.flushIdle()
+		case <-ctx.Done():
+			// Jobs still held by middlewares are not waited for. A hold is released when the update
+			// made from the held ones is processed or dropped: its worker drops it once abandon is set,
+			// or redispatch fails on the cancelled context, and finish releases the holds as not processed.
+			// The held jobs then complete as dropped, so their done is never called and a Poller does
+			// not confirm their updates, which are received again.
+			d.mu.Lock()
+			d.abandon = true
+			d.mu.Unlock()
+			d.cancel()
+
+			return ctx.Err()
+		}
+	}
+}
+
+// flushIdle flushes the dispatcher if no update is queued or being processed, so that the updates
+// held back wait for no more updates while the dispatcher is shutting down.
+func (d *Dispatcher) flushIdle() {
+	d.mu.Lock()
+	idle := len(d.lanes) == 0
+	d.mu.Unlock()
+
+	if idle {
+		d.Flush()
+	}
+}
+
+func (d *Dispatcher) work() {
+	for key := range d.runnable {
+		d.mu.Lock()
+		l := d.lanes[key]
+		j := l.pending[0]
+		l.pending = l.pending[1:]
+		abandon := d.abandon
+		d.mu.Unlock()
+
+		if !abandon {
+			d.process(j)
+		}
+
+		d.mu.Lock()
+
+		if len(l.pending) > 0 {
+			d.runnable <- key
+		} else {
+			delete(d.lanes, key)
+		}
+
+		d.mu.Unlock()
+		<-d.slots
+		d.complete(j, !abandon)
+	}
+}
+
+func (d *Dispatcher) process(j *job) {
+	c := NewContext(d.ctx, j.bot, j.update)
+	c.dispatcher = d
+	c.job = j
+
+	var finish func(HandlerRun)
+
+	if d.Instrumentation != nil {
+		finish = d.Instrumentation.StartHandler(c)
+	}
+
+	start := time.Now()
+	err := chain(d.Handler, []Middleware{Recover()}).HandleUpdate(c)
+
+	if finish != nil {
+		finish(HandlerRun{Kind: j.update.Kind(), Duration: time.Since(start), Err: err})
+	}
+
+	if err == nil || err == ErrNotHandled || err == ErrForbidden {
+		return
+	}
+
+	d.reportError(c, err)
+}
+
+func (d *Dispatcher) reportError(c *Context, err error) {
+	if d.OnError != nil {
+		d.OnError(c, err)
+	} else {
+		log.Printf("%v", err)
+	}
+}
+
+// updateKey returns the key of the lane the update is processed in.
+func updateKey(bot *Bot, u *Update) laneKey {
+	if chat := u.EffectiveChat(); chat != nil {
+		return laneKey{bot: bot, id: chat.Id}
+	}
+
+	if user := u.EffectiveUser(); user != nil {
+		return laneKey{bot: bot, id: int64(user.Id)}
+	}
+
+	return laneKey{bot: bot, id: int64(u.UpdateId), unordered: true}
+}
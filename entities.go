@@ -0,0 +1,253 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+// Types of message entities.
+const (
+	EntityMention       = "mention"
+	EntityHashtag       = "hashtag"
+	EntityCashtag       = "cashtag"
+	EntityBotCommand    = "bot_command"
+	EntityUrl           = "url"
+	EntityEmail         = "email"
+	EntityPhoneNumber   = "phone_number"
+	EntityBold          = "bold"
+	EntityItalic        = "italic"
+	EntityUnderline     = "underline"
+	EntityStrikethrough = "strikethrough"
+	EntityCode          = "code"
+	EntityPre           = "pre"
+	EntityTextLink      = "text_link"
+	EntityTextMention   = "text_mention"
+)
+
+// Utf16Len returns the length of s in UTF-16 code units, the unit of the offsets and lengths
+// of message entities.
+func Utf16Len(s string) int {
+	n := 0
+
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+
+	return n
+}
+
+// utf16ToByteOffset converts an offset in UTF-16 code units to a byte offset in the UTF-8 string s.
+// Offsets past the end of s are clamped to len(s).
+func utf16ToByteOffset(s string, units int) int {
+	for i, r := range s {
+		if units <= 0 {
+			return i
+		}
+
+		if r >= 0x10000 {
+			units -= 2
+		} else {
+			units--
+		}
+	}
+
+	return len(s)
+}
+
+// Range returns the byte offsets of the start and the end of the entity in text, so that
+// text[start:end] is the text of the entity. Offsets past the end of text are clamped to len(text).
+func (e MessageEntity) Range(text string) (start, end int) {
+	start = utf16ToByteOffset(text, e.Offset)
+	end = start + utf16ToByteOffset(text[start:], e.Length)
+
+	return start, end
+}
+
+// Text returns the part of text the entity refers to.
+func (e MessageEntity) Text(text string) string {
+	start, end := e.Range(text)
+
+	return text[start:end]
+}
+
+// textEntities returns the text of the message and its entities, or the caption and the caption
+// entities if the message has no text.
+func (m *Message) textEntities() (string, []MessageEntity) {
+	if m.Text != "" {
+		if m.Entities == nil {
+			return m.Text, nil
+		}
+
+		return m.Text, *m.Entities
+	}
+
+	if m.CaptionEntities == nil {
+		return m.Caption, nil
+	}
+
+	return m.Caption, *m.CaptionEntities
+}
+
+// EntityText returns the part of the text of the message, or of the caption if the message has no text,
+// the entity refers to.
+func (m *Message) EntityText(e MessageEntity) string {
+	text, _ := m.textEntities()
+
+	return e.Text(text)
+}
+
+// EntitiesOfType returns the entities of the text or the caption of the message with one of the types.
+func (m *Message) EntitiesOfType(types ...string) []MessageEntity {
+	_, entities := m.textEntities()
+	var found []MessageEntity
+
+	for _, e := range entities {
+		for _, t := range types {
+			if e.Type == t {
+				found = append(found, e)
+
+				break
+			}
+		}
+	}
+
+	return found
+}
+
+func (m *Message) entityTexts(t string) []string {
+	var texts []string
+
+	for _, e := range m.EntitiesOfType(t) {
+		texts = append(texts, m.EntityText(e))
+	}
+
+	return texts
+}
+
+// Mentions returns the @usernames mentioned in the text or the caption of the message.
+// Mentions of users without usernames are text_mention entities with the User field set.
+func (m *Message) Mentions() []string {
+	return m.entityTexts(EntityMention)
+}
+
+// Hashtags returns the #hashtags in the text or the caption of the message.
+func (m *Message) Hashtags() []string {
+	return m.entityTexts(EntityHashtag)
+}
+
+// Commands returns the bot commands in the text or the caption of the message, e.g. “/start@jobs_bot”.
+func (m *Message) Commands() []string {
+	return m.entityTexts(EntityBotCommand)
+}
+
+// Urls returns the URLs in the text or the caption of the message and the URLs of its text links.
+func (m *Message) Urls() []string {
+	var urls []string
+
+	for _, e := range m.EntitiesOfType(EntityUrl, EntityTextLink) {
+		if e.Type == EntityTextLink {
+			urls = append(urls, e.Url)
+		} else {
+			urls = append(urls, m.EntityText(e))
+		}
+	}
+
+	return urls
+}
+
+// ShiftEntities returns the entities moved by delta UTF-16 code units, e.g. after text of that length
+// was prepended to the text. Entities moved before the start of the text are clipped or dropped.
+func ShiftEntities(entities []MessageEntity, delta int) []MessageEntity {
+	shifted := make([]MessageEntity, 0, len(entities))
+
+	for _, e := range entities {
+		e.Offset += delta
+
+		if e.Offset < 0 {
+			e.Length += e.Offset
+			e.Offset = 0
+		}
+
+		if e.Length > 0 {
+			shifted = append(shifted, e)
+		}
+	}
+
+	return shifted
+}
+
+// ClipEntities returns the entities for the part of the text between the start and the end offsets
+// in UTF-16 code units: entities outside the part are dropped, entities crossing its bounds are clipped,
+// and offsets are made relative to start.
+func ClipEntities(entities []MessageEntity, start, end int) []MessageEntity {
+	clipped := make([]MessageEntity, 0, len(entities))
+
+	for _, e := range entities {
+		from, to := e.Offset, e.Offset+e.Length
+
+		if from < start {
+			from = start
+		}
+
+		if to > end {
+			to = end
+		}
+
+		if from >= to {
+			continue
+		}
+
+		e.Offset, e.Length = from-start, to-from
+		clipped = append(clipped, e)
+	}
+
+	return clipped
+}
+
+// SliceText returns text[start:end], where start and end are byte offsets, with the entities clipped
+// to the slice.
+func SliceText(text string, entities []MessageEntity, start, end int) (string, []MessageEntity) {
+	return text[start:end], ClipEntities(entities, Utf16Len(text[:start]), Utf16Len(text[:end]))
+}
+
+// ReplaceText replaces text[start:end], where start and end are byte offsets, with repl and adjusts
+// the entities: entities after the replaced part are shifted, entities enclosing it grow or shrink,
+// entities partly overlapping it are clipped, and entities inside it are dropped. Text inserted at
+// the bounds of an entity is not made part of it.
+func ReplaceText(text string, entities []MessageEntity, start, end int, repl string) (string, []MessageEntity) {
+	s, e := Utf16Len(text[:start]), Utf16Len(text[:end])
+	n := Utf16Len(repl)
+	delta := n - (e - s)
+	adjusted := make([]MessageEntity, 0, len(entities))
+
+	for _, ent := range entities {
+		from, to := ent.Offset, ent.Offset+ent.Length
+
+		switch {
+		case to <= s:
+		case from >= e:
+			from, to = from+delta, to+delta
+		case from <= s && to >= e:
+			to += delta
+		case from >= s && to <= e:
+			continue
+		case from < s:
+			to = s
+		default:
+			from, to = s+n, to+delta
+		}
+
+		if from >= to {
+			continue
+		}
+
+		ent.Offset, ent.Length = from, to-from
+		adjusted = append(adjusted, ent)
+	}
+
+	return text[:start] + repl + text[end:], adjusted
+}
@@ -0,0 +1,139 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"reflect"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+// entityText has characters of one, two and four UTF-8 bytes, the last one being two UTF-16 code units.
+const entityText = "👍 héllo @bob #tag https://example.com"
+
+var textEntities = []tgbot.MessageEntity{
+	{Type: tgbot.EntityTextLink, Offset: 0, Length: 2, Url: "https://t.me"},
+	{Type: tgbot.EntityMention, Offset: 9, Length: 4},
+	{Type: tgbot.EntityHashtag, Offset: 14, Length: 4},
+	{Type: tgbot.EntityUrl, Offset: 19, Length: 19},
+}
+
+func TestUtf16Len(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello", 5},
+		{"héllo", 5},
+		{"👍", 2},
+		{"a👍b", 4},
+		{entityText, 38},
+	} {
+		if got := tgbot.Utf16Len(tc.text); got != tc.want {
+			t.Errorf("Utf16Len(%q) = %d, want %d", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestMessageEntityText(t *testing.T) {
+	want := []string{"👍", "@bob", "#tag", "https://example.com"}
+
+	for i, e := range textEntities {
+		if got := e.Text(entityText); got != want[i] {
+			t.Errorf("text of %s entity = %q, want %q", e.Type, got, want[i])
+		}
+	}
+
+	// Entities past the end of the text are clamped.
+	if got := (tgbot.MessageEntity{Offset: 30, Length: 20}).Text(entityText); got != "mple.com" {
+		t.Errorf("text of entity past the end = %q, want %q", got, "mple.com")
+	}
+}
+
+func TestMessageEntityAccessors(t *testing.T) {
+	entities := textEntities
+	msg := &tgbot.Message{Caption: entityText, CaptionEntities: &entities}
+
+	if got, want := msg.Mentions(), []string{"@bob"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Mentions() = %q, want %q", got, want)
+	}
+
+	if got, want := msg.Hashtags(), []string{"#tag"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Hashtags() = %q, want %q", got, want)
+	}
+
+	if got, want := msg.Urls(), []string{"https://t.me", "https://example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Urls() = %q, want %q", got, want)
+	}
+}
+
+func TestShiftEntities(t *testing.T) {
+	got := tgbot.ShiftEntities(textEntities[:2], -10)
+	want := []tgbot.MessageEntity{{Type: tgbot.EntityMention, Offset: 0, Length: 3}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ShiftEntities(-10) = %+v, want %+v", got, want)
+	}
+}
+
+func TestClipEntities(t *testing.T) {
+	got := tgbot.ClipEntities(textEntities, 1, 16)
+	want := []tgbot.MessageEntity{
+		{Type: tgbot.EntityTextLink, Offset: 0, Length: 1, Url: "https://t.me"},
+		{Type: tgbot.EntityMention, Offset: 8, Length: 4},
+		{Type: tgbot.EntityHashtag, Offset: 13, Length: 2},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ClipEntities(1, 16) = %+v, want %+v", got, want)
+	}
+}
+
+func TestSliceText(t *testing.T) {
+	// Byte offsets of "@bob #ta", after the four bytes of the emoji and the two bytes of the é.
+	text, entities := tgbot.SliceText(entityText, textEntities, 12, 20)
+
+	if text != "@bob #ta" {
+		t.Fatalf("text = %q", text)
+	}
+
+	var texts []string
+
+	for _, e := range entities {
+		texts = append(texts, e.Text(text))
+	}
+
+	if want := []string{"@bob", "#ta"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("entity texts = %q, want %q", texts, want)
+	}
+}
+
+func TestReplaceText(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		start, end int
+		repl       string
+		want       []string
+	}{
+		{"insert before an entity", 12, 12, "😀😀", []string{"👍", "@bob", "#tag", "https://example.com"}},
+		{"replace inside an entity", 13, 16, "alice", []string{"👍", "@alice", "#tag", "https://example.com"}},
+		{"replace across entities", 14, 19, "", []string{"👍", "@b", "ag", "https://example.com"}},
+		{"replace an entity", 0, 4, "", []string{"@bob", "#tag", "https://example.com"}},
+	} {
+		text, entities := tgbot.ReplaceText(entityText, textEntities, tc.start, tc.end, tc.repl)
+		var texts []string
+
+		for _, e := range entities {
+			texts = append(texts, e.Text(text))
+		}
+
+		if !reflect.DeepEqual(texts, tc.want) {
+			t.Errorf("%s: entity texts of %q = %q, want %q", tc.name, text, texts, tc.want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import "time"
+
+// AddCurrency makes the currency known to LookupCurrency, e.g. a currency with three digits past
+// the decimal point, which Telegram does not list.
+func AddCurrency(c *Currency) {
+	LookupCurrency(c.Code)
+	currencies[c.Code] = c
+}
+
+// FireQuietTimer runs the quiet period timer of the media group as it was set for the message
+// of the generation gen, the number of messages of the group collected so far.
+func (mc *MediaGroupCollector) FireQuietTimer(bot *Bot, chatId int64, groupId string, gen int) {
+	mc.release(mediaGroupKey{bot: bot, chatId: chatId, groupId: groupId}, gen)
+}
+
+// Reserve schedules the messages of a request of the bot to the chat as if it was made at now.
+func (l *Limiter) Reserve(bot *Bot, chatId string, messages int, now time.Time) time.Time {
+	return l.reserve(bot, chatId, messages, now)
+}
+
+var MessageCount = messageCount
@@ -5,6 +5,8 @@
 
 package tgbot
 
+import "fmt"
+
 type InputFile struct {
 	FileId string
 	FileURL string
@@ -22,3 +24,52 @@ func InputFileFromDisk(path string) InputFile {
 func (f *InputFile) IsOnDisk() bool {
 	return f.FilePath != ""
 }
+
+func InputFileFromId(fileId string) InputFile {
+	return InputFile{FileId: fileId}
+}
+
+func (f *InputFile) IsEmpty() bool {
+	return f.FileId == "" && f.FileURL == "" && f.FilePath == ""
+}
+
+// value returns the string that identifies a file which is not uploaded from disk,
+// either its file_id or its HTTP URL.
+func (f *InputFile) value() string {
+	if f.FileId != "" {
+		return f.FileId
+	}
+
+	return f.FileURL
+}
+
+// InputSticker describes a sticker that is uploaded to a sticker set. Exactly one of PngSticker
+// and TgsSticker must be set.
+type InputSticker struct {
+	// PNG image with the sticker, must be up to 512 kilobytes in size, dimensions must not exceed 512px,
+	// and either width or height must be exactly 512px.
+	PngSticker InputFile
+	// TGS animation with the sticker. Animated stickers can only be uploaded from disk.
+	TgsSticker InputFile
+	// One or more emoji corresponding to the sticker
+	Emojis string
+	// Optional. Position where the mask should be placed on faces
+	MaskPosition *MaskPosition
+}
+
+// file returns the form field name and the file of the sticker.
+func (s *InputSticker) file() (string, InputFile, error) {
+	if s.PngSticker.IsEmpty() == s.TgsSticker.IsEmpty() {
+		return "", InputFile{}, fmt.Errorf("tgbot: exactly one of png or tgs sticker must be set")
+	}
+
+	if !s.TgsSticker.IsEmpty() {
+		if !s.TgsSticker.IsOnDisk() {
+			return "", InputFile{}, fmt.Errorf("tgbot: animated stickers must be uploaded from disk")
+		}
+
+		return "tgs_sticker", s.TgsSticker, nil
+	}
+
+	return "png_sticker", s.PngSticker, nil
+}
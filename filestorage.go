@@ -0,0 +1,288 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// minCompactRecords is the number of log records below which a FileStorage is never compacted.
+const minCompactRecords = 1024
+
+// FileStorage is a Storage that keeps values in memory and records every change in an append-only
+// log file, from which the values are restored when the storage is opened again. The log is compacted
+// when it holds more than twice as many records as there are values.
+type FileStorage struct {
+	// Sync makes every change flushed to disk before the method returns. Without it, changes survive
+	// a crash of the process but may be lost on a crash of the machine.
+	Sync bool
+
+	mu      sync.Mutex
+	mem     *MemoryStorage
+	path    string
+	file    *os.File
+	records int
+}
+
+// fileRecord is a line of the FileStorage log.
+type fileRecord struct {
+	Key     string `json:"k"`
+	Value   []byte `json:"v,omitempty"`
+	Expires int64  `json:"e,omitempty"`
+	Deleted bool   `json:"d,omitempty"`
+}
+
+// OpenFileStorage opens the storage kept in the file at path, creating it if it does not exist.
+// A record truncated by a crash at the end of the log is discarded.
+func OpenFileStorage(path string) (*FileStorage, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileStorage{
+		mem:  NewMemoryStorage(),
+		path: path,
+		file: file,
+	}
+
+	if err = s.replay(); err != nil {
+		file.Close()
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileStorage) replay() error {
+	r := bufio.NewReader(s.file)
+	now := time.Now()
+	var offset int64
+
+	for {
+		line, err := r.ReadBytes('\n')
+
+		if err == io.EOF {
+			// A line without a newline is a record that was not written completely.
+			if len(line) > 0 {
+				if err = s.file.Truncate(offset); err != nil {
+					return err
+				}
+			}
+
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var rec fileRecord
+
+		if err = json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("tgbot: corrupt storage record at offset %d of %s: %v", offset, s.path, err)
+		}
+
+		offset += int64(len(line))
+		s.records++
+
+		if rec.Deleted {
+			delete(s.mem.entries, rec.Key)
+			continue
+		}
+
+		e := &memoryEntry{value: rec.Value}
+
+		if rec.Expires != 0 {
+			e.expires = time.Unix(0, rec.Expires)
+		}
+
+		if e.expired(now) {
+			delete(s.mem.entries, rec.Key)
+		} else {
+			s.mem.entries[rec.Key] = e
+		}
+	}
+
+	_, err := s.file.Seek(offset, io.SeekStart)
+
+	return err
+}
+
+func (s *FileStorage) Get(key string) ([]byte, bool, error) {
+	return s.mem.Get(key)
+}
+
+func (s *FileStorage) Set(key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The record is made from the new entry, which a concurrent Get may already remove if it expired.
+	s.mem.mu.Lock()
+	rec := entryRecord(key, s.mem.set(key, value, ttl))
+	s.mem.mu.Unlock()
+
+	return s.append(rec)
+}
+
+func (s *FileStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.mem.Delete(key); err != nil {
+		return err
+	}
+
+	return s.append(fileRecord{Key: key, Deleted: true})
+}
+
+func (s *FileStorage) CompareAndSwap(key string, old, new []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mem.mu.Lock()
+	swapped, e := s.mem.compareAndSwap(key, old, new, ttl)
+	s.mem.mu.Unlock()
+
+	if !swapped {
+		return false, nil
+	}
+
+	if e == nil {
+		return true, s.append(fileRecord{Key: key, Deleted: true})
+	}
+
+	return true, s.append(entryRecord(key, e))
+}
+
+// Close closes the log file.
+func (s *FileStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+func (s *FileStorage) append(rec fileRecord) error {
+	line, err := json.Marshal(rec)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	if s.Sync {
+		if err = s.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	s.records++
+
+	s.mem.mu.Lock()
+	live := len(s.mem.entries)
+	s.mem.mu.Unlock()
+
+	if s.records > minCompactRecords && s.records > 2*live {
+		return s.compact()
+	}
+
+	return nil
+}
+
+// compact replaces the log with one that has a single record for every live value.
+func (s *FileStorage) compact() error {
+	var buf bytes.Buffer
+	records := 0
+	now := time.Now()
+
+	s.mem.mu.Lock()
+
+	for key, e := range s.mem.entries {
+		if e.expired(now) {
+			continue
+		}
+
+		line, err := json.Marshal(entryRecord(key, e))
+
+		if err != nil {
+			s.mem.mu.Unlock()
+
+			return err
+		}
+
+		buf.Write(append(line, '\n'))
+		records++
+	}
+
+	s.mem.mu.Unlock()
+
+	tmp := s.path + ".tmp"
+
+	if err := writeFileSync(tmp, buf.Bytes()); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_RDWR|os.O_APPEND, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	s.file.Close()
+	s.file = file
+	s.records = records
+
+	return nil
+}
+
+func entryRecord(key string, e *memoryEntry) fileRecord {
+	rec := fileRecord{Key: key, Value: e.value}
+
+	if !e.expires.IsZero() {
+		rec.Expires = e.expires.UnixNano()
+	}
+
+	return rec
+}
+
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	if err = file.Sync(); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,178 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+func openFileStorage(t *testing.T, path string) *tgbot.FileStorage {
+	t.Helper()
+	s, err := tgbot.OpenFileStorage(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func checkValue(t *testing.T, s tgbot.Storage, key, want string) {
+	t.Helper()
+	value, ok, err := s.Get(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want == "" && ok {
+		t.Errorf("%s = %q, want no value", key, value)
+	} else if want != "" && string(value) != want {
+		t.Errorf("%s = %q, %v, want %q", key, value, ok, want)
+	}
+}
+
+func TestFileStorageReplaysLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.log")
+	s := openFileStorage(t, path)
+
+	for _, err := range []error{
+		s.Set("a", []byte("1"), 0),
+		s.Set("b", []byte("2"), 0),
+		s.Set("a", []byte("3"), time.Hour),
+		s.Set("expired", []byte("4"), time.Millisecond),
+		s.Delete("b"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if swapped, err := s.CompareAndSwap("c", nil, []byte("5"), 0); err != nil || !swapped {
+		t.Fatalf("CompareAndSwap = %v, %v", swapped, err)
+	}
+
+	s.Close()
+	time.Sleep(2 * time.Millisecond)
+	s = openFileStorage(t, path)
+
+	checkValue(t, s, "a", "3")
+	checkValue(t, s, "b", "")
+	checkValue(t, s, "c", "5")
+	checkValue(t, s, "expired", "")
+}
+
+func TestFileStorageDiscardsTruncatedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.log")
+	s := openFileStorage(t, path)
+
+	if err := s.Set("a", []byte("1"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Close()
+
+	// The process crashed while writing the next record.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.WriteString(`{"k":"b","v":`)
+	f.Close()
+
+	s = openFileStorage(t, path)
+	checkValue(t, s, "a", "1")
+	checkValue(t, s, "b", "")
+
+	if err = s.Set("c", []byte("2"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Close()
+	s = openFileStorage(t, path)
+	checkValue(t, s, "a", "1")
+	checkValue(t, s, "c", "2")
+}
+
+func TestFileStorageRejectsCorruptRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.log")
+
+	if err := ioutil.WriteFile(path, []byte("{\"k\":\"a\"}\nnot json\n{\"k\":\"b\"}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err := tgbot.OpenFileStorage(path); err == nil {
+		s.Close()
+		t.Error("storage with a corrupt record was opened")
+	}
+}
+
+func TestFileStorageCompactsLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.log")
+	s := openFileStorage(t, path)
+
+	for i := 0; i < 3000; i++ {
+		if err := s.Set(fmt.Sprintf("key%d", i%3), []byte(fmt.Sprint(i)), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := bytes.Count(data, []byte("\n")); lines > 2000 {
+		t.Errorf("log has %d records for 3 values", lines)
+	}
+
+	s.Close()
+	s = openFileStorage(t, path)
+
+	for i, want := range []string{"2997", "2998", "2999"} {
+		checkValue(t, s, fmt.Sprintf("key%d", i), want)
+	}
+}
+
+func TestFileStorageSetsValuesExpiringAtOnce(t *testing.T) {
+	s := openFileStorage(t, filepath.Join(t.TempDir(), "storage.log"))
+	var wg sync.WaitGroup
+
+	// Values set with a tiny TTL may be removed by Get before Set records them.
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 500; j++ {
+				if err := s.Set("k", []byte("v"), time.Nanosecond); err != nil {
+					t.Error(err)
+
+					return
+				}
+
+				s.Get("k")
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,117 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import "regexp"
+
+// Filter reports whether a handler should process the update.
+type Filter func(c *Context) bool
+
+// And returns a filter that matches updates matched by all filters.
+func And(filters ...Filter) Filter {
+	return func(c *Context) bool {
+		for _, f := range filters {
+			if !f(c) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Or returns a filter that matches updates matched by any of the filters.
+func Or(filters ...Filter) Filter {
+	return func(c *Context) bool {
+		for _, f := range filters {
+			if f(c) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Not returns a filter that matches updates not matched by f.
+func Not(f Filter) Filter {
+	return func(c *Context) bool {
+		return !f(c)
+	}
+}
+
+// ChatType matches updates from chats of the types, e.g. “private”, “group”, “supergroup” or “channel”.
+func ChatType(types ...string) Filter {
+	return func(c *Context) bool {
+		chat := c.Chat()
+
+		if chat == nil {
+			return false
+		}
+
+		for _, t := range types {
+			if chat.Type == t {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// TextMatches matches updates whose text, as returned by Context.Text, matches the regular expression.
+// It panics if the expression cannot be parsed.
+func TextMatches(pattern string) Filter {
+	return TextRegexp(regexp.MustCompile(pattern))
+}
+
+// TextRegexp matches updates whose text, as returned by Context.Text, matches re.
+func TextRegexp(re *regexp.Regexp) Filter {
+	return func(c *Context) bool {
+		return re.MatchString(c.Text())
+	}
+}
+
+// HasPhoto matches updates with a photo message.
+func HasPhoto() Filter {
+	return func(c *Context) bool {
+		msg := c.Message()
+
+		return msg != nil && msg.Photo != nil && len(*msg.Photo) > 0
+	}
+}
+
+// FromUser matches updates caused by the users with the identifiers.
+func FromUser(ids ...int) Filter {
+	return func(c *Context) bool {
+		user := c.Sender()
+
+		if user == nil {
+			return false
+		}
+
+		for _, id := range ids {
+			if user.Id == id {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// ReplyToBot matches messages that reply to a message sent by the bot.
+func ReplyToBot() Filter {
+	return func(c *Context) bool {
+		msg := c.Message()
+
+		if msg == nil || msg.ReplyToMessage == nil || msg.ReplyToMessage.From == nil || c.Bot.Me == nil {
+			return false
+		}
+
+		return msg.ReplyToMessage.From.Id == c.Bot.Me.Id
+	}
+}
@@ -0,0 +1,92 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"context"
+	"time"
+)
+
+// Instrumentation observes the Bot API requests of a bot, set as Bot.Instrumentation, and the handler
+// runs of a dispatcher, set as Dispatcher.Instrumentation. A tracer starts a span in the Start methods
+// and ends it in the returned function; Metrics collects metrics exposed to Prometheus.
+type Instrumentation interface {
+	// StartApiCall is called before a request for the method is sent. The returned function, if not nil,
+	// is called when the response was read or the request failed.
+	StartApiCall(ctx context.Context, bot *Bot, method string) func(call ApiCall)
+	// StartHandler is called before the handler processes the update of c. The returned function,
+	// if not nil, is called when the handler returned.
+	StartHandler(c *Context) func(run HandlerRun)
+}
+
+// ApiCall describes a completed Bot API request.
+type ApiCall struct {
+	// Method is the Bot API method, or “file/” followed by the file path for file downloads.
+	Method string
+	// Status is the HTTP status code of the response, zero if the request failed.
+	Status int
+	// ErrorCode and Description are those of an error response.
+	ErrorCode   int
+	Description string
+	// Duration is the time from sending the request to reading the whole response.
+	Duration time.Duration
+	// RequestBytes is the size of the request body, -1 if unknown.
+	RequestBytes int64
+	// ResponseBytes is the size of the response body.
+	ResponseBytes int
+	// Err is the error that made the request fail, without the token. Error responses are not failures.
+	Err error
+}
+
+// HandlerRun describes a completed run of a handler.
+type HandlerRun struct {
+	// Kind is the kind of the processed update.
+	Kind UpdateKind
+	// Duration is the time the handler took.
+	Duration time.Duration
+	// Err is the error returned by the handler, such as ErrNotHandled, ErrForbidden or a *PanicError.
+	Err error
+}
+
+type multiInstrumentation []Instrumentation
+
+// Instrumentations returns an Instrumentation that calls each of ins in turn, e.g. to both trace
+// requests and collect metrics.
+func Instrumentations(ins ...Instrumentation) Instrumentation {
+	return multiInstrumentation(ins)
+}
+
+func (m multiInstrumentation) StartApiCall(ctx context.Context, bot *Bot, method string) func(call ApiCall) {
+	finish := make([]func(ApiCall), 0, len(m))
+
+	for _, in := range m {
+		if f := in.StartApiCall(ctx, bot, method); f != nil {
+			finish = append(finish, f)
+		}
+	}
+
+	return func(call ApiCall) {
+		for _, f := range finish {
+			f(call)
+		}
+	}
+}
+
+func (m multiInstrumentation) StartHandler(c *Context) func(run HandlerRun) {
+	finish := make([]func(HandlerRun), 0, len(m))
+
+	for _, in := range m {
+		if f := in.StartHandler(c); f != nil {
+			finish = append(finish, f)
+		}
+	}
+
+	return func(run HandlerRun) {
+		for _, f := range finish {
+			f(run)
+		}
+	}
+}
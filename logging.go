@@ -0,0 +1,261 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultPayloadLogLimit is the number of bytes of a request or a response logged with Bot.LogPayloads.
+const DefaultPayloadLogLimit = 1024
+
+// RedactedToken replaces the token of a bot in errors and log records.
+const RedactedToken = "<redacted>"
+
+// Logger receives the log records of a bot, with args as alternating keys and values. Its methods
+// match those of *slog.Logger, so a *slog.Logger can be used as a Logger; StdLogger adapts
+// a standard *log.Logger. Records are filtered by level by the logger.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// LogLevel is the minimum level of the records written by a StdLogger.
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+type stdLogger struct {
+	logger *log.Logger
+	level  LogLevel
+}
+
+// StdLogger returns a Logger that writes the records at the level and above to the standard logger l,
+// or to the default standard logger if l is nil, as the message followed by key=value pairs.
+func StdLogger(l *log.Logger, level LogLevel) Logger {
+	if l == nil {
+		l = log.New(log.Writer(), log.Prefix(), log.Flags())
+	}
+
+	return &stdLogger{logger: l, level: level}
+}
+
+func (l *stdLogger) Debug(msg string, args ...interface{}) { l.log(LevelDebug, "DEBUG", msg, args) }
+func (l *stdLogger) Info(msg string, args ...interface{})  { l.log(LevelInfo, "INFO", msg, args) }
+func (l *stdLogger) Warn(msg string, args ...interface{})  { l.log(LevelWarn, "WARN", msg, args) }
+func (l *stdLogger) Error(msg string, args ...interface{}) { l.log(LevelError, "ERROR", msg, args) }
+
+func (l *stdLogger) log(level LogLevel, name, msg string, args []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(name + " " + msg)
+
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&sb, " %v=%q", args[i], fmt.Sprint(args[i+1]))
+		} else {
+			fmt.Fprintf(&sb, " !BADKEY=%q", fmt.Sprint(args[i]))
+		}
+	}
+
+	l.logger.Output(3, sb.String())
+}
+
+// String describes the bot without revealing its token.
+func (bot *Bot) String() string {
+	if bot.Me != nil && bot.Me.Username != "" {
+		return "Bot(@" + bot.Me.Username + ")"
+	}
+
+	return "Bot(" + botId(bot) + ")"
+}
+
+// GoString describes the bot without revealing its token.
+func (bot *Bot) GoString() string {
+	return "&tgbot.Bot{" + bot.String() + "}"
+}
+
+// redact replaces the token of the bot in s.
+func (bot *Bot) redact(s string) string {
+	if bot.Token == "" {
+		return s
+	}
+
+	return strings.Replace(s, bot.Token, RedactedToken, -1)
+}
+
+// redactError returns err with the token of the bot replaced. The URL of a *url.Error is redacted
+// in place; other errors mentioning the token are replaced with an error with the redacted message.
+func (bot *Bot) redactError(err error) error {
+	if err == nil || bot.Token == "" {
+		return err
+	}
+
+	if ue, ok := err.(*url.Error); ok {
+		ue.URL = bot.redact(ue.URL)
+	}
+
+	if msg := err.Error(); strings.Contains(msg, bot.Token) {
+		return errors.New(bot.redact(msg))
+	}
+
+	return err
+}
+
+// do sends the request for the Bot API method or file and returns the body of the response.
+// Errors never contain the token. Requests are traced with the Logger of the bot and reported
+// to its Instrumentation. The time spent waiting for the RateLimiter is not part of the reported duration.
+func (bot *Bot) do(req *http.Request, method string, params map[string]string) (*http.Response, []byte, error) {
+	if bot.RateLimiter != nil {
+		err := bot.RateLimiter.Wait(req.Context(), bot, method, params["chat_id"], messageCount(method, params))
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var finish func(ApiCall)
+
+	if bot.Instrumentation != nil {
+		finish = bot.Instrumentation.StartApiCall(req.Context(), bot, method)
+	}
+
+	start := time.Now()
+	call := ApiCall{Method: method, RequestBytes: req.ContentLength}
+	resp, err := bot.client().Do(req)
+
+	var body []byte
+
+	if err == nil {
+		body, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		call.Status = resp.StatusCode
+	}
+
+	call.Duration = time.Since(start)
+	call.ResponseBytes = len(body)
+	call.Err = bot.redactError(err)
+
+	if call.Err == nil && call.Status != http.StatusOK {
+		var apiResp apiResponse
+
+		if json.Unmarshal(body, &apiResp) == nil {
+			call.ErrorCode = apiResp.ErrorCode
+			call.Description = bot.redact(apiResp.Description)
+		}
+	}
+
+	bot.logRequest(&call, params, body)
+
+	if finish != nil {
+		finish(call)
+	}
+
+	if call.Err != nil {
+		return nil, nil, call.Err
+	}
+
+	return resp, body, nil
+}
+
+func (bot *Bot) logRequest(call *ApiCall, params map[string]string, body []byte) {
+	if bot.Logger == nil {
+		return
+	}
+
+	args := []interface{}{"bot", bot.String(), "method", call.Method, "duration", call.Duration}
+
+	if call.Err != nil {
+		bot.Logger.Error("tgbot: request failed", append(args, "error", call.Err.Error())...)
+
+		return
+	}
+
+	args = append(args, "status", call.Status, "bytes", call.ResponseBytes)
+
+	if bot.LogPayloads {
+		args = append(args, "params", bot.payload(formatParams(params)))
+
+		if !strings.HasPrefix(call.Method, "file/") {
+			args = append(args, "response", bot.payload(string(body)))
+		}
+	}
+
+	if call.Status != http.StatusOK {
+		if call.Description != "" {
+			args = append(args, "error_code", call.ErrorCode, "description", call.Description)
+		}
+
+		bot.Logger.Warn("tgbot: request returned an error", args...)
+
+		return
+	}
+
+	bot.Logger.Debug("tgbot: request", args...)
+}
+
+// payload returns s redacted and truncated to the payload log limit of the bot.
+func (bot *Bot) payload(s string) string {
+	limit := bot.PayloadLogLimit
+
+	if limit <= 0 {
+		limit = DefaultPayloadLogLimit
+	}
+
+	s = bot.redact(s)
+
+	if len(s) > limit {
+		for limit > 0 && !utf8.RuneStart(s[limit]) {
+			limit--
+		}
+
+		return fmt.Sprintf("%s… (%d bytes)", s[:limit], len(s))
+	}
+
+	return s
+}
+
+func formatParams(params map[string]string) string {
+	names := make([]string, 0, len(params))
+
+	for name := range params {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var sb strings.Builder
+
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+
+		fmt.Fprintf(&sb, "%s=%q", name, params[name])
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,112 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+	"github.com/modern-dev/tgbot-go/tgbottest"
+)
+
+// logRecorder is a Logger that keeps the records as text.
+type logRecorder struct {
+	mu      sync.Mutex
+	records []string
+}
+
+func (l *logRecorder) record(level, msg string, args []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, fmt.Sprint(level, " ", msg, args))
+}
+
+func (l *logRecorder) Debug(msg string, args ...interface{}) { l.record("DEBUG", msg, args) }
+func (l *logRecorder) Info(msg string, args ...interface{})  { l.record("INFO", msg, args) }
+func (l *logRecorder) Warn(msg string, args ...interface{})  { l.record("WARN", msg, args) }
+func (l *logRecorder) Error(msg string, args ...interface{}) { l.record("ERROR", msg, args) }
+
+func uploadFile(t *testing.T) tgbot.InputFile {
+	path := filepath.Join(t.TempDir(), "cat.jpg")
+
+	if err := ioutil.WriteFile(path, []byte("meow"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return tgbot.InputFileFromDisk(path)
+}
+
+func TestUploadErrorsAreRedacted(t *testing.T) {
+	srv := tgbottest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("sendPhoto", func(call *tgbottest.Call) (interface{}, error) {
+		return nil, errors.New("storage is down")
+	})
+
+	logger := &logRecorder{}
+	bot := srv.NewBot()
+	bot.Logger = logger
+	bot.LogPayloads = true
+
+	_, err := bot.SendPhoto("42", uploadFile(t), &tgbot.SendPhotoOptions{Caption: "token " + bot.Token})
+
+	if err == nil || !strings.HasPrefix(err.Error(), "tgbot: sendPhoto failed") {
+		t.Errorf("SendPhoto error = %v, want a tgbot error", err)
+	}
+
+	if len(logger.records) != 1 || !strings.HasPrefix(logger.records[0], "WARN") {
+		t.Fatalf("records = %q, want a warning", logger.records)
+	}
+
+	if strings.Contains(logger.records[0], bot.Token) || !strings.Contains(logger.records[0], tgbot.RedactedToken) {
+		t.Errorf("record is not redacted: %s", logger.records[0])
+	}
+}
+
+func TestFailedRequestErrorsAreRedacted(t *testing.T) {
+	srv := tgbottest.NewServer()
+	srv.Close()
+
+	logger := &logRecorder{}
+	bot := srv.NewBot()
+	bot.Logger = logger
+
+	if _, err := bot.SendPhoto("42", uploadFile(t), nil); err == nil || strings.Contains(err.Error(), bot.Token) {
+		t.Errorf("upload error = %v, want an error without the token", err)
+	}
+
+	if _, err := bot.GetMe(); err == nil || strings.Contains(err.Error(), bot.Token) {
+		t.Errorf("request error = %v, want an error without the token", err)
+	}
+
+	if len(logger.records) != 2 {
+		t.Fatalf("records = %q, want two errors", logger.records)
+	}
+
+	for _, record := range logger.records {
+		if !strings.HasPrefix(record, "ERROR") || strings.Contains(record, bot.Token) {
+			t.Errorf("record = %s, want a redacted error", record)
+		}
+	}
+}
+
+func TestBotStringHidesToken(t *testing.T) {
+	bot := &tgbot.Bot{Token: "123:abc"}
+
+	for _, s := range []string{bot.String(), fmt.Sprintf("%v", bot), fmt.Sprintf("%#v", bot)} {
+		if strings.Contains(s, "abc") {
+			t.Errorf("%q reveals the token", s)
+		}
+	}
+}
@@ -0,0 +1,747 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parse modes of message text and captions.
+const (
+	ParseModeHTML       = "HTML"
+	ParseModeMarkdownV2 = "MarkdownV2"
+)
+
+// markupRenderer writes the text of a message with the markup of a parse mode.
+type markupRenderer interface {
+	open(sb *strings.Builder, e MessageEntity)
+	close(sb *strings.Builder, e MessageEntity)
+	escape(sb *strings.Builder, s string, code bool)
+}
+
+// EntitiesToHTML returns the text formatted with the entities as Telegram-flavored HTML.
+// Overlapping entities are closed and reopened so that the tags are properly nested. Entities that
+// Telegram detects by itself, such as mentions and URLs, are written as plain text.
+func EntitiesToHTML(text string, entities []MessageEntity) string {
+	return renderMarkup(text, entities, htmlRenderer{})
+}
+
+// EntitiesToMarkdownV2 returns the text formatted with the entities as MarkdownV2. Overlapping entities
+// are closed and reopened so that the markup is properly nested. Entities that Telegram detects
+// by itself, such as mentions and URLs, are written as plain text.
+func EntitiesToMarkdownV2(text string, entities []MessageEntity) string {
+	return renderMarkup(text, entities, markdownRenderer{})
+}
+
+// TextHTML returns the text of the message formatted as HTML.
+func (m *Message) TextHTML() string {
+	return EntitiesToHTML(m.Text, derefEntities(m.Entities))
+}
+
+// CaptionHTML returns the caption of the message formatted as HTML.
+func (m *Message) CaptionHTML() string {
+	return EntitiesToHTML(m.Caption, derefEntities(m.CaptionEntities))
+}
+
+// TextMarkdownV2 returns the text of the message formatted as MarkdownV2.
+func (m *Message) TextMarkdownV2() string {
+	return EntitiesToMarkdownV2(m.Text, derefEntities(m.Entities))
+}
+
+// CaptionMarkdownV2 returns the caption of the message formatted as MarkdownV2.
+func (m *Message) CaptionMarkdownV2() string {
+	return EntitiesToMarkdownV2(m.Caption, derefEntities(m.CaptionEntities))
+}
+
+func derefEntities(entities *[]MessageEntity) []MessageEntity {
+	if entities == nil {
+		return nil
+	}
+
+	return *entities
+}
+
+// isFormatting reports whether the entity is written as markup rather than detected by Telegram.
+func isFormatting(e MessageEntity) bool {
+	switch e.Type {
+	case EntityBold, EntityItalic, EntityUnderline, EntityStrikethrough, EntityCode, EntityPre,
+		EntityTextLink, EntityTextMention:
+		return true
+	}
+
+	return false
+}
+
+func isCode(e MessageEntity) bool {
+	return e.Type == EntityCode || e.Type == EntityPre
+}
+
+type markupSpan struct {
+	entity     MessageEntity
+	start, end int
+}
+
+// markupSpans returns the byte ranges of the formatting entities ordered by start with enclosing
+// entities first. Entities inside code or overlapping it are dropped, as code cannot be formatted.
+func markupSpans(text string, entities []MessageEntity) []markupSpan {
+	var spans []markupSpan
+
+	for _, e := range entities {
+		if !isFormatting(e) {
+			continue
+		}
+
+		start, end := e.Range(text)
+
+		if start < end {
+			spans = append(spans, markupSpan{entity: e, start: start, end: end})
+		}
+	}
+
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+
+		return spans[i].end > spans[j].end
+	})
+
+	var kept []markupSpan
+
+	for _, s := range spans {
+		overlapsCode := false
+
+		for _, k := range kept {
+			if (isCode(k.entity) || isCode(s.entity)) && s.start < k.end && k.start < s.end {
+				overlapsCode = true
+
+				break
+			}
+		}
+
+		if !overlapsCode {
+			kept = append(kept, s)
+		}
+	}
+
+	return kept
+}
+
+func renderMarkup(text string, entities []MessageEntity, r markupRenderer) string {
+	spans := markupSpans(text, entities)
+	var stack []markupSpan
+	var sb strings.Builder
+	next := 0
+	pos := 0
+
+	for pos < len(text) || len(stack) > 0 {
+		// Close the entities ending here, together with the entities opened after them,
+		// which are reopened to keep the markup nested.
+		ending := -1
+
+		for i, s := range stack {
+			if s.end <= pos {
+				ending = i
+
+				break
+			}
+		}
+
+		if ending >= 0 {
+			var reopen []markupSpan
+
+			for i := len(stack) - 1; i >= ending; i-- {
+				r.close(&sb, stack[i].entity)
+
+				if stack[i].end > pos {
+					reopen = append([]markupSpan{stack[i]}, reopen...)
+				}
+			}
+
+			stack = stack[:ending]
+
+			for _, s := range reopen {
+				r.open(&sb, s.entity)
+				stack = append(stack, s)
+			}
+
+			continue
+		}
+
+		for next < len(spans) && spans[next].start <= pos {
+			r.open(&sb, spans[next].entity)
+			stack = append(stack, spans[next])
+			next++
+		}
+
+		end := len(text)
+
+		if next < len(spans) && spans[next].start < end {
+			end = spans[next].start
+		}
+
+		code := false
+
+		for _, s := range stack {
+			if s.end < end {
+				end = s.end
+			}
+
+			code = code || isCode(s.entity)
+		}
+
+		r.escape(&sb, text[pos:end], code)
+		pos = end
+	}
+
+	return sb.String()
+}
+
+type htmlRenderer struct{}
+
+func (htmlRenderer) open(sb *strings.Builder, e MessageEntity) {
+	switch e.Type {
+	case EntityBold:
+		sb.WriteString("<b>")
+	case EntityItalic:
+		sb.WriteString("<i>")
+	case EntityUnderline:
+		sb.WriteString("<u>")
+	case EntityStrikethrough:
+		sb.WriteString("<s>")
+	case EntityCode:
+		sb.WriteString("<code>")
+	case EntityPre:
+		if e.Language != "" {
+			sb.WriteString(`<pre><code class="language-` + html.EscapeString(e.Language) + `">`)
+		} else {
+			sb.WriteString("<pre>")
+		}
+	case EntityTextLink:
+		sb.WriteString(`<a href="` + html.EscapeString(e.Url) + `">`)
+	case EntityTextMention:
+		sb.WriteString(`<a href="` + userLink(e.User) + `">`)
+	}
+}
+
+func (htmlRenderer) close(sb *strings.Builder, e MessageEntity) {
+	switch e.Type {
+	case EntityBold:
+		sb.WriteString("</b>")
+	case EntityItalic:
+		sb.WriteString("</i>")
+	case EntityUnderline:
+		sb.WriteString("</u>")
+	case EntityStrikethrough:
+		sb.WriteString("</s>")
+	case EntityCode:
+		sb.WriteString("</code>")
+	case EntityPre:
+		if e.Language != "" {
+			sb.WriteString("</code></pre>")
+		} else {
+			sb.WriteString("</pre>")
+		}
+	case EntityTextLink, EntityTextMention:
+		sb.WriteString("</a>")
+	}
+}
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (htmlRenderer) escape(sb *strings.Builder, s string, code bool) {
+	htmlEscaper.WriteString(sb, s)
+}
+
+type markdownRenderer struct{}
+
+func (markdownRenderer) open(sb *strings.Builder, e MessageEntity) {
+	switch e.Type {
+	case EntityBold:
+		sb.WriteString("*")
+	case EntityItalic:
+		writeUnderscores(sb, "_")
+	case EntityUnderline:
+		writeUnderscores(sb, "__")
+	case EntityStrikethrough:
+		sb.WriteString("~")
+	case EntityCode:
+		sb.WriteString("`")
+	case EntityPre:
+		sb.WriteString("```" + e.Language + "\n")
+	case EntityTextLink, EntityTextMention:
+		sb.WriteString("[")
+	}
+}
+
+func (markdownRenderer) close(sb *strings.Builder, e MessageEntity) {
+	switch e.Type {
+	case EntityBold:
+		sb.WriteString("*")
+	case EntityItalic:
+		writeUnderscores(sb, "_")
+	case EntityUnderline:
+		writeUnderscores(sb, "__")
+	case EntityStrikethrough:
+		sb.WriteString("~")
+	case EntityCode:
+		sb.WriteString("`")
+	case EntityPre:
+		// The closing fence follows the code directly: a newline before it would be part of the code.
+		sb.WriteString("```")
+	case EntityTextLink:
+		sb.WriteString("](" + markdownLinkEscaper.Replace(e.Url) + ")")
+	case EntityTextMention:
+		sb.WriteString("](" + userLink(e.User) + ")")
+	}
+}
+
+// writeUnderscores writes the markup of italic or underlined text. Telegram reads underscores greedily
+// as underline markup, so adjacent markup is separated with a carriage return, which Telegram ignores.
+func writeUnderscores(sb *strings.Builder, markup string) {
+	if strings.HasSuffix(sb.String(), "_") {
+		sb.WriteString("\r")
+	}
+
+	sb.WriteString(markup)
+}
+
+var (
+	markdownEscaper     = regexp.MustCompile("[_*\\[\\]()~`>#+\\-=|{}.!\\\\]")
+	markdownCodeEscaper = strings.NewReplacer("`", "\\`", "\\", "\\\\")
+	markdownLinkEscaper = strings.NewReplacer(")", "\\)", "\\", "\\\\")
+)
+
+func (markdownRenderer) escape(sb *strings.Builder, s string, code bool) {
+	if code {
+		markdownCodeEscaper.WriteString(sb, s)
+	} else {
+		sb.WriteString(markdownEscaper.ReplaceAllString(s, "\\$0"))
+	}
+}
+
+// EscapeMarkdownV2 escapes the characters of s that have a meaning in MarkdownV2.
+func EscapeMarkdownV2(s string) string {
+	return markdownEscaper.ReplaceAllString(s, "\\$0")
+}
+
+func userLink(user *User) string {
+	if user == nil {
+		return "tg://user?id=0"
+	}
+
+	return "tg://user?id=" + strconv.Itoa(user.Id)
+}
+
+var (
+	htmlTagRe  = regexp.MustCompile(`^<(/?)([a-zA-Z]+)((?:\s+[a-zA-Z-]+\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'>]+))*)\s*(/?)>`)
+	htmlAttrRe = regexp.MustCompile(`([a-zA-Z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+type openTag struct {
+	name   string
+	entity MessageEntity
+	start  int
+}
+
+// ParseHTML parses text formatted with Telegram-flavored HTML into plain text and entities, the reverse
+// of EntitiesToHTML. It supports the tags b, strong, i, em, u, ins, s, strike, del, a, code and pre,
+// with a code tag with a language-* class inside pre for the language, and links to tg://user?id=
+// for text mentions. Unknown, unclosed and misnested tags are errors.
+func ParseHTML(s string) (string, []MessageEntity, error) {
+	var sb strings.Builder
+	var entities []MessageEntity
+	var stack []openTag
+	length := 0
+
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+
+		if i != 0 {
+			if i < 0 {
+				i = len(s)
+			}
+
+			text := html.UnescapeString(s[:i])
+			sb.WriteString(text)
+			length += Utf16Len(text)
+			s = s[i:]
+
+			continue
+		}
+
+		m := htmlTagRe.FindStringSubmatch(s)
+
+		if m == nil {
+			return "", nil, fmt.Errorf("tgbot: malformed HTML tag at %q", truncate(s, 20))
+		}
+
+		s = s[len(m[0]):]
+		name := strings.ToLower(m[2])
+
+		if m[1] == "/" {
+			if len(stack) == 0 || stack[len(stack)-1].name != name {
+				return "", nil, fmt.Errorf("tgbot: unexpected HTML end tag </%s>", name)
+			}
+
+			tag := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if tag.entity.Type == "" {
+				continue
+			}
+
+			if length > tag.start {
+				tag.entity.Offset, tag.entity.Length = tag.start, length-tag.start
+				entities = append(entities, tag.entity)
+			}
+
+			continue
+		}
+
+		attrs := make(map[string]string)
+
+		for _, a := range htmlAttrRe.FindAllStringSubmatch(m[3], -1) {
+			attrs[strings.ToLower(a[1])] = html.UnescapeString(a[2] + a[3] + a[4])
+		}
+
+		tag := openTag{name: name, start: length}
+
+		switch name {
+		case "b", "strong":
+			tag.entity.Type = EntityBold
+		case "i", "em":
+			tag.entity.Type = EntityItalic
+		case "u", "ins":
+			tag.entity.Type = EntityUnderline
+		case "s", "strike", "del":
+			tag.entity.Type = EntityStrikethrough
+		case "pre":
+			tag.entity.Type = EntityPre
+		case "code":
+			if n := len(stack); n > 0 && stack[n-1].entity.Type == EntityPre {
+				// The code tag inside pre only sets the language.
+				stack[n-1].entity.Language = strings.TrimPrefix(attrs["class"], "language-")
+			} else {
+				tag.entity.Type = EntityCode
+			}
+		case "a":
+			href := attrs["href"]
+
+			if id := strings.TrimPrefix(href, "tg://user?id="); id != href {
+				userId, err := strconv.Atoi(id)
+
+				if err != nil {
+					return "", nil, fmt.Errorf("tgbot: invalid user link %q", href)
+				}
+
+				tag.entity.Type = EntityTextMention
+				tag.entity.User = &User{Id: userId}
+			} else {
+				tag.entity.Type = EntityTextLink
+				tag.entity.Url = href
+			}
+		default:
+			return "", nil, fmt.Errorf("tgbot: unsupported HTML tag <%s>", name)
+		}
+
+		if m[4] == "/" {
+			continue
+		}
+
+		stack = append(stack, tag)
+	}
+
+	if len(stack) > 0 {
+		return "", nil, fmt.Errorf("tgbot: unclosed HTML tag <%s>", stack[len(stack)-1].name)
+	}
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		if entities[i].Offset != entities[j].Offset {
+			return entities[i].Offset < entities[j].Offset
+		}
+
+		return entities[i].Length > entities[j].Length
+	})
+
+	return sb.String(), mergeEntities(entities), nil
+}
+
+// mergeEntities joins adjacent entities that differ only in their range, such as the parts of
+// an overlapping entity that EntitiesToHTML closed and reopened.
+func mergeEntities(entities []MessageEntity) []MessageEntity {
+	var merged []MessageEntity
+
+	for _, e := range entities {
+		joined := false
+
+		for i := range merged {
+			m := &merged[i]
+
+			if m.Offset+m.Length == e.Offset && m.Type == e.Type && m.Url == e.Url &&
+				m.Language == e.Language && sameUser(m.User, e.User) {
+				m.Length += e.Length
+				joined = true
+
+				break
+			}
+		}
+
+		if !joined {
+			merged = append(merged, e)
+		}
+	}
+
+	return merged
+}
+
+func sameUser(a, b *User) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Id == b.Id
+}
+
+type openMarker struct {
+	marker string
+	entity MessageEntity
+	start  int
+	// pos is the byte offset of the marker in the text, for a [ that is kept there until it
+	// turns out to start a link.
+	pos int
+}
+
+var (
+	markdownCodeUnescaper = strings.NewReplacer("\\`", "`", "\\\\", "\\")
+	markdownLinkUnescaper = strings.NewReplacer("\\)", ")", "\\\\", "\\")
+)
+
+// ParseMarkdownV2 parses text formatted with MarkdownV2 into plain text and entities, the reverse
+// of EntitiesToMarkdownV2. Unclosed markup is an error; reserved characters that are not escaped
+// are read as text, including a [ that no ]( follows.
+func ParseMarkdownV2(s string) (string, []MessageEntity, error) {
+	var text []byte
+	var entities []MessageEntity
+	var stack []openMarker
+	length := 0
+
+	write := func(part string) {
+		text = append(text, part...)
+		length += Utf16Len(part)
+	}
+
+	closeMarker := func(i int) MessageEntity {
+		m := stack[i]
+		stack = append(stack[:i], stack[i+1:]...)
+		m.entity.Offset, m.entity.Length = m.start, length-m.start
+
+		return m.entity
+	}
+
+	toggle := func(marker, entityType string) {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].marker == marker {
+				if e := closeMarker(i); e.Length > 0 {
+					entities = append(entities, e)
+				}
+
+				return
+			}
+		}
+
+		stack = append(stack, openMarker{marker: marker, entity: MessageEntity{Type: entityType}, start: length})
+	}
+
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			_, n := utf8.DecodeRuneInString(s[i+1:])
+			write(s[i+1 : i+1+n])
+			i += 1 + n
+		case s[i] == '\r':
+			i++
+		case strings.HasPrefix(s[i:], "```"):
+			end := indexUnescaped(s[i+3:], "```")
+
+			if end < 0 {
+				return "", nil, errors.New("tgbot: unclosed pre-formatted block")
+			}
+
+			body, language := s[i+3:i+3+end], ""
+
+			if j := strings.IndexByte(body, '\n'); j >= 0 {
+				body, language = body[j+1:], body[:j]
+			}
+
+			start := length
+			write(markdownCodeUnescaper.Replace(body))
+
+			if length > start {
+				entities = append(entities, MessageEntity{Type: EntityPre, Offset: start, Length: length - start, Language: language})
+			}
+
+			i += 3 + end + 3
+		case s[i] == '`':
+			end := indexUnescaped(s[i+1:], "`")
+
+			if end < 0 {
+				return "", nil, errors.New("tgbot: unclosed inline code")
+			}
+
+			start := length
+			write(markdownCodeUnescaper.Replace(s[i+1 : i+1+end]))
+
+			if length > start {
+				entities = append(entities, MessageEntity{Type: EntityCode, Offset: start, Length: length - start})
+			}
+
+			i += 1 + end + 1
+		case strings.HasPrefix(s[i:], "__"):
+			toggle("__", EntityUnderline)
+			i += 2
+		case s[i] == '_':
+			toggle("_", EntityItalic)
+			i++
+		case s[i] == '*':
+			toggle("*", EntityBold)
+			i++
+		case s[i] == '~':
+			toggle("~", EntityStrikethrough)
+			i++
+		case s[i] == '[':
+			// The [ is text unless a ]( closes it.
+			stack = append(stack, openMarker{marker: "[", start: length, pos: len(text)})
+			write("[")
+			i++
+		case strings.HasPrefix(s[i:], "](") && hasMarker(stack, "["):
+			end := indexUnescaped(s[i+2:], ")")
+
+			if end < 0 {
+				return "", nil, errors.New("tgbot: unclosed link URL")
+			}
+
+			href := markdownLinkUnescaper.Replace(s[i+2 : i+2+end])
+			k := len(stack) - 1
+
+			for stack[k].marker != "[" {
+				k--
+			}
+
+			text, entities = removeBracket(text, entities, stack, stack[k])
+			length--
+
+			if id := strings.TrimPrefix(href, "tg://user?id="); id != href {
+				userId, err := strconv.Atoi(id)
+
+				if err != nil {
+					return "", nil, fmt.Errorf("tgbot: invalid user link %q", href)
+				}
+
+				stack[k].entity = MessageEntity{Type: EntityTextMention, User: &User{Id: userId}}
+			} else {
+				stack[k].entity = MessageEntity{Type: EntityTextLink, Url: href}
+			}
+
+			if e := closeMarker(k); e.Length > 0 {
+				entities = append(entities, e)
+			}
+
+			i += 2 + end + 1
+		default:
+			_, n := utf8.DecodeRuneInString(s[i:])
+			write(s[i : i+n])
+			i += n
+		}
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].marker != "[" {
+			return "", nil, fmt.Errorf("tgbot: unclosed markup %q", stack[i].marker)
+		}
+	}
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		if entities[i].Offset != entities[j].Offset {
+			return entities[i].Offset < entities[j].Offset
+		}
+
+		return entities[i].Length > entities[j].Length
+	})
+
+	return string(text), mergeEntities(entities), nil
+}
+
+// indexUnescaped returns the index of the first instance of sep in s that is not escaped with
+// a backslash, or -1.
+func indexUnescaped(s, sep string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+
+			continue
+		}
+
+		if strings.HasPrefix(s[i:], sep) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// removeBracket removes the [ of the marker b, which starts a link, from the text, and moves
+// the entities and the open markers after it.
+func removeBracket(text []byte, entities []MessageEntity, stack []openMarker, b openMarker) ([]byte, []MessageEntity) {
+	text = append(text[:b.pos], text[b.pos+1:]...)
+	kept := entities[:0]
+
+	for _, e := range entities {
+		if e.Offset > b.start {
+			e.Offset--
+		} else if e.Offset+e.Length > b.start {
+			e.Length--
+		}
+
+		if e.Length > 0 {
+			kept = append(kept, e)
+		}
+	}
+
+	for i := range stack {
+		if stack[i].start > b.start {
+			stack[i].start--
+			stack[i].pos--
+		}
+	}
+
+	return text, kept
+}
+
+func hasMarker(stack []openMarker, marker string) bool {
+	for _, m := range stack {
+		if m.marker == marker {
+			return true
+		}
+	}
+
+	return false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	return s[:n]
+}
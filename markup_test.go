@@ -0,0 +1,133 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"reflect"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+func TestPreEntityMarkdownV2RoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		entities []tgbot.MessageEntity
+		markdown string
+	}{
+		{
+			name:     "code without trailing newline",
+			text:     "x := 1",
+			entities: []tgbot.MessageEntity{{Type: tgbot.EntityPre, Offset: 0, Length: 6}},
+			markdown: "```\nx := 1```",
+		},
+		{
+			name:     "code with trailing newline",
+			text:     "x := 1\n",
+			entities: []tgbot.MessageEntity{{Type: tgbot.EntityPre, Offset: 0, Length: 7}},
+			markdown: "```\nx := 1\n```",
+		},
+		{
+			name:     "language and surrounding text",
+			text:     "see fmt.Println() here",
+			entities: []tgbot.MessageEntity{{Type: tgbot.EntityPre, Offset: 4, Length: 13, Language: "go"}},
+			markdown: "see ```go\nfmt.Println()``` here",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markdown := tgbot.EntitiesToMarkdownV2(tt.text, tt.entities)
+
+			if markdown != tt.markdown {
+				t.Errorf("EntitiesToMarkdownV2 = %q, want %q", markdown, tt.markdown)
+			}
+
+			text, entities, err := tgbot.ParseMarkdownV2(markdown)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if text != tt.text || !reflect.DeepEqual(entities, tt.entities) {
+				t.Errorf("ParseMarkdownV2 = %q, %+v, want %q, %+v", text, entities, tt.text, tt.entities)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownV2Brackets(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		text     string
+		entities []tgbot.MessageEntity
+	}{
+		{
+			name:     "unmatched bracket",
+			markdown: "a [b c",
+			text:     "a [b c",
+		},
+		{
+			name:     "bracket without a URL",
+			markdown: "[a] b",
+			text:     "[a] b",
+		},
+		{
+			name:     "unmatched bracket inside bold",
+			markdown: "*a [b* c",
+			text:     "a [b c",
+			entities: []tgbot.MessageEntity{{Type: tgbot.EntityBold, Offset: 0, Length: 4}},
+		},
+		{
+			name:     "unmatched bracket before a link",
+			markdown: "[x [*y*](http://a.b)",
+			text:     "[x y",
+			entities: []tgbot.MessageEntity{
+				{Type: tgbot.EntityBold, Offset: 3, Length: 1},
+				{Type: tgbot.EntityTextLink, Offset: 3, Length: 1, Url: "http://a.b"},
+			},
+		},
+		{
+			name:     "link inside bold",
+			markdown: "*a [b](http://a.b) c*",
+			text:     "a b c",
+			entities: []tgbot.MessageEntity{
+				{Type: tgbot.EntityBold, Offset: 0, Length: 5},
+				{Type: tgbot.EntityTextLink, Offset: 2, Length: 1, Url: "http://a.b"},
+			},
+		},
+		{
+			name:     "unmatched bracket after a link",
+			markdown: "[a](http://a.b) [b",
+			text:     "a [b",
+			entities: []tgbot.MessageEntity{{Type: tgbot.EntityTextLink, Offset: 0, Length: 1, Url: "http://a.b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, entities, err := tgbot.ParseMarkdownV2(tt.markdown)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if text != tt.text || !reflect.DeepEqual(entities, tt.entities) {
+				t.Errorf("ParseMarkdownV2(%q) = %q, %+v, want %q, %+v", tt.markdown, text, entities, tt.text, tt.entities)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownV2RejectsUnclosedMarkup(t *testing.T) {
+	for _, markdown := range []string{"*a", "a _b", "`a", "```a", "[a](http://a.b"} {
+		if _, _, err := tgbot.ParseMarkdownV2(markdown); err == nil {
+			t.Errorf("ParseMarkdownV2(%q) succeeded", markdown)
+		}
+	}
+}
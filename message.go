@@ -0,0 +1,233 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+// MessageKind is the kind of the content of a Message, named after the field of the message that is set.
+type MessageKind string
+
+const (
+	MessageText      MessageKind = "text"
+	MessageAudio     MessageKind = "audio"
+	MessageDocument  MessageKind = "document"
+	MessageAnimation MessageKind = "animation"
+	MessageGame      MessageKind = "game"
+	MessagePhoto     MessageKind = "photo"
+	MessageSticker   MessageKind = "sticker"
+	MessageVideo     MessageKind = "video"
+	MessageVoice     MessageKind = "voice"
+	MessageVideoNote MessageKind = "video_note"
+	MessageContact   MessageKind = "contact"
+	MessageLocation  MessageKind = "location"
+	MessageVenue     MessageKind = "venue"
+	MessagePoll      MessageKind = "poll"
+	MessageInvoice   MessageKind = "invoice"
+	// MessagePassportData is the kind of messages with Telegram Passport data shared with the bot.
+	MessagePassportData MessageKind = "passport_data"
+
+	// Kinds of service messages.
+	MessageNewChatMembers        MessageKind = "new_chat_members"
+	MessageLeftChatMember        MessageKind = "left_chat_member"
+	MessageNewChatTitle          MessageKind = "new_chat_title"
+	MessageNewChatPhoto          MessageKind = "new_chat_photo"
+	MessageDeleteChatPhoto       MessageKind = "delete_chat_photo"
+	MessageGroupChatCreated      MessageKind = "group_chat_created"
+	MessageSupergroupChatCreated MessageKind = "supergroup_chat_created"
+	MessageChannelChatCreated    MessageKind = "channel_chat_created"
+	MessageMigrateToChatId       MessageKind = "migrate_to_chat_id"
+	MessageMigrateFromChatId     MessageKind = "migrate_from_chat_id"
+	MessagePinnedMessage         MessageKind = "pinned_message"
+	MessageSuccessfulPayment     MessageKind = "successful_payment"
+	MessageConnectedWebsite      MessageKind = "connected_website"
+
+	// MessageUnknown is the kind of messages with none of the known fields set.
+	MessageUnknown MessageKind = "unknown"
+)
+
+// IsService reports whether the kind is the kind of a service message.
+func (k MessageKind) IsService() bool {
+	switch k {
+	case MessageNewChatMembers, MessageLeftChatMember, MessageNewChatTitle, MessageNewChatPhoto,
+		MessageDeleteChatPhoto, MessageGroupChatCreated, MessageSupergroupChatCreated, MessageChannelChatCreated,
+		MessageMigrateToChatId, MessageMigrateFromChatId, MessagePinnedMessage, MessageSuccessfulPayment,
+		MessageConnectedWebsite:
+		return true
+	}
+
+	return false
+}
+
+// Kind returns the kind of the message. Animations are reported as MessageAnimation, although
+// their Document field is set too, and venues as MessageVenue, although their Location field is set too.
+func (m *Message) Kind() MessageKind {
+	switch {
+	case m.Animation != nil:
+		return MessageAnimation
+	case m.Audio != nil:
+		return MessageAudio
+	case m.Document != nil:
+		return MessageDocument
+	case m.Game != nil:
+		return MessageGame
+	case m.Photo != nil && len(*m.Photo) > 0:
+		return MessagePhoto
+	case m.Sticker != nil:
+		return MessageSticker
+	case m.Video != nil:
+		return MessageVideo
+	case m.Voice != nil:
+		return MessageVoice
+	case m.VideoNote != nil:
+		return MessageVideoNote
+	case m.Contact != nil:
+		return MessageContact
+	case m.Venue != nil:
+		return MessageVenue
+	case m.Location != nil:
+		return MessageLocation
+	case m.Poll != nil:
+		return MessagePoll
+	case m.Invoice != nil:
+		return MessageInvoice
+	case m.PassportData != nil:
+		return MessagePassportData
+	case m.Text != "":
+		return MessageText
+	case m.NewChatMembers != nil && len(*m.NewChatMembers) > 0:
+		return MessageNewChatMembers
+	case m.LeftChatMember != nil:
+		return MessageLeftChatMember
+	case m.NewChatTitle != "":
+		return MessageNewChatTitle
+	case m.NewChatPhoto != nil && len(*m.NewChatPhoto) > 0:
+		return MessageNewChatPhoto
+	case m.DeleteChatPhoto:
+		return MessageDeleteChatPhoto
+	case m.GroupChatCreated:
+		return MessageGroupChatCreated
+	case m.SupergroupChatCreated:
+		return MessageSupergroupChatCreated
+	case m.ChannelChatCreated:
+		return MessageChannelChatCreated
+	case m.MigrateToChatId != 0:
+		return MessageMigrateToChatId
+	case m.MigrateFromChatId != 0:
+		return MessageMigrateFromChatId
+	case m.PinnedMessage != nil:
+		return MessagePinnedMessage
+	case m.SuccessfulPayment != nil:
+		return MessageSuccessfulPayment
+	case m.ConnectedWebsite != "":
+		return MessageConnectedWebsite
+	}
+
+	return MessageUnknown
+}
+
+// IsService reports whether the message is a service message, e.g. about new chat members or a pinned message.
+func (m *Message) IsService() bool {
+	return m.Kind().IsService()
+}
+
+// IsCommand reports whether the text or the caption of the message starts with a bot command.
+func (m *Message) IsCommand() bool {
+	return ParseCommand(m) != nil
+}
+
+// LargestPhoto returns the largest size of the photo of the message, or nil if the message has no photo.
+func (m *Message) LargestPhoto() *PhotoSize {
+	if m.Photo == nil {
+		return nil
+	}
+
+	return largestPhotoSize(*m.Photo)
+}
+
+func largestPhotoSize(sizes []PhotoSize) *PhotoSize {
+	var largest *PhotoSize
+
+	for i := range sizes {
+		if largest == nil || sizes[i].Width*sizes[i].Height > largest.Width*largest.Height {
+			largest = &sizes[i]
+		}
+	}
+
+	return largest
+}
+
+// FileId returns the identifier of the file of the message, which can be passed to GetFile or
+// InputFileFromId: the file of an animation, audio, document, sticker, video, voice message or video note,
+// or the largest size of a photo. Returns an empty string if the message has no file.
+func (m *Message) FileId() string {
+	switch m.Kind() {
+	case MessageAnimation:
+		return m.Animation.FileId
+	case MessageAudio:
+		return m.Audio.FileId
+	case MessageDocument:
+		return m.Document.FileId
+	case MessagePhoto:
+		return m.LargestPhoto().FileId
+	case MessageSticker:
+		return m.Sticker.FileId
+	case MessageVideo:
+		return m.Video.FileId
+	case MessageVoice:
+		return m.Voice.FileId
+	case MessageVideoNote:
+		return m.VideoNote.FileId
+	}
+
+	return ""
+}
+
+// MessageKinds matches updates with a message of one of the kinds.
+func MessageKinds(kinds ...MessageKind) Filter {
+	return func(c *Context) bool {
+		msg := c.Message()
+
+		if msg == nil {
+			return false
+		}
+
+		kind := msg.Kind()
+
+		for _, k := range kinds {
+			if kind == k {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// ServiceMessage matches updates with a service message.
+func ServiceMessage() Filter {
+	return func(c *Context) bool {
+		msg := c.Message()
+
+		return msg != nil && msg.IsService()
+	}
+}
+
+// HasFile matches updates with a message that has a file, as returned by Message.FileId.
+func HasFile() Filter {
+	return func(c *Context) bool {
+		msg := c.Message()
+
+		return msg != nil && msg.FileId() != ""
+	}
+}
+
+// AnyCommand matches updates with a message that starts with a bot command, including commands
+// addressed to other bots. Use Commands to match only the commands addressed to the bot.
+func AnyCommand() Filter {
+	return func(c *Context) bool {
+		msg := c.Message()
+
+		return msg != nil && msg.IsCommand()
+	}
+}
@@ -0,0 +1,311 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the upper bounds in seconds of the duration histograms of Metrics.
+// They extend up to a minute for getUpdates long polling.
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Metrics is an Instrumentation that collects metrics of Bot API requests and handler runs, and
+// an http.Handler that serves them in the Prometheus text exposition format:
+//
+//	metrics := tgbot.NewMetrics()
+//	bot.Instrumentation = metrics
+//	dispatcher.Instrumentation = metrics
+//	http.Handle("/metrics", metrics)
+//
+// The metrics are labelled with the identifier of the bot, so a single Metrics can serve several bots.
+// File downloads are reported with the method “file”.
+type Metrics struct {
+	// Namespace prefixes the names of the metrics, “tgbot” if empty.
+	Namespace string
+	// Buckets are the upper bounds of the duration histograms, DefaultDurationBuckets if nil.
+	// They must be sorted and not be changed after the first observation.
+	Buckets []float64
+
+	mu       sync.Mutex
+	families map[string]*metricFamily
+}
+
+type metricFamily struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+	series map[string]*metricSeries
+}
+
+type metricSeries struct {
+	labelValues []string
+	value       float64
+	buckets     []uint64
+	count       uint64
+}
+
+// NewMetrics returns an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// StartApiCall implements Instrumentation.
+func (m *Metrics) StartApiCall(ctx context.Context, bot *Bot, method string) func(call ApiCall) {
+	return func(call ApiCall) {
+		m.ObserveApiCall(bot, call)
+	}
+}
+
+// StartHandler implements Instrumentation.
+func (m *Metrics) StartHandler(c *Context) func(run HandlerRun) {
+	return func(run HandlerRun) {
+		m.ObserveHandlerRun(c.Bot, run)
+	}
+}
+
+// ObserveApiCall records the request made by the bot.
+func (m *Metrics) ObserveApiCall(bot *Bot, call ApiCall) {
+	method := call.Method
+
+	if strings.HasPrefix(method, "file/") {
+		method = "file"
+	}
+
+	status := strconv.Itoa(call.Status)
+
+	if call.Err != nil {
+		status = "error"
+	}
+
+	id := botId(bot)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.add("api_requests_total", "Number of Bot API requests by HTTP status, or error for failed requests.",
+		[]string{"bot", "method", "status"}, []string{id, method, status}, 1)
+	m.observe("api_request_duration_seconds", "Duration of Bot API requests.",
+		[]string{"bot", "method"}, []string{id, method}, call.Duration)
+
+	if call.ErrorCode != 0 {
+		m.add("api_errors_total", "Number of Bot API error responses by error code.",
+			[]string{"bot", "method", "error_code"}, []string{id, method, strconv.Itoa(call.ErrorCode)}, 1)
+	}
+
+	if call.RequestBytes > 0 {
+		m.add("api_request_bytes_total", "Size of the bodies of Bot API requests.",
+			[]string{"bot", "method"}, []string{id, method}, float64(call.RequestBytes))
+	}
+
+	m.add("api_response_bytes_total", "Size of the bodies of Bot API responses.",
+		[]string{"bot", "method"}, []string{id, method}, float64(call.ResponseBytes))
+}
+
+// ObserveHandlerRun records the run of a handler for an update received by the bot.
+func (m *Metrics) ObserveHandlerRun(bot *Bot, run HandlerRun) {
+	result := "ok"
+
+	switch run.Err.(type) {
+	case nil:
+	case *PanicError:
+		result = "panic"
+	default:
+		switch run.Err {
+		case ErrNotHandled:
+			result = "not_handled"
+		case ErrForbidden:
+			result = "forbidden"
+		default:
+			result = "error"
+		}
+	}
+
+	kind := string(run.Kind)
+
+	if kind == "" {
+		kind = "unknown"
+	}
+
+	id := botId(bot)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.add("handler_runs_total", "Number of handler runs by result: ok, error, not_handled, forbidden or panic.",
+		[]string{"bot", "kind", "result"}, []string{id, kind, result}, 1)
+	m.observe("handler_duration_seconds", "Duration of handler runs.",
+		[]string{"bot", "kind"}, []string{id, kind}, run.Duration)
+}
+
+// add adds the value to a counter. m.mu must be held.
+func (m *Metrics) add(name, help string, labels, values []string, value float64) {
+	m.series(name, help, "counter", labels, values).value += value
+}
+
+// observe adds the duration to a histogram. m.mu must be held.
+func (m *Metrics) observe(name, help string, labels, values []string, d time.Duration) {
+	s := m.series(name, help, "histogram", labels, values)
+	seconds := d.Seconds()
+
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(m.buckets()))
+	}
+
+	for i, bound := range m.buckets() {
+		if seconds <= bound {
+			s.buckets[i]++
+		}
+	}
+
+	s.value += seconds
+	s.count++
+}
+
+// series returns the series of the metric with the label values, creating it if needed. m.mu must be held.
+func (m *Metrics) series(name, help, typ string, labels, values []string) *metricSeries {
+	if m.families == nil {
+		m.families = make(map[string]*metricFamily)
+	}
+
+	f, ok := m.families[name]
+
+	if !ok {
+		f = &metricFamily{name: name, help: help, typ: typ, labels: labels, series: make(map[string]*metricSeries)}
+		m.families[name] = f
+	}
+
+	key := strings.Join(values, "\x00")
+	s, ok := f.series[key]
+
+	if !ok {
+		s = &metricSeries{labelValues: values}
+		f.series[key] = s
+	}
+
+	return s
+}
+
+func (m *Metrics) buckets() []float64 {
+	if m.Buckets != nil {
+		return m.Buckets
+	}
+
+	return DefaultDurationBuckets
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.Write(w)
+}
+
+// Write writes the metrics to w in the Prometheus text exposition format, sorted by name and labels.
+func (m *Metrics) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	namespace := m.Namespace
+
+	if namespace == "" {
+		namespace = "tgbot"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.families))
+
+	for name := range m.families {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := m.families[name]
+		fullName := namespace + "_" + f.name
+		keys := make([]string, 0, len(f.series))
+
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		fmt.Fprintf(bw, "# HELP %s %s\n", fullName, escapeHelp(f.help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", fullName, f.typ)
+
+		for _, key := range keys {
+			s := f.series[key]
+			labels := formatLabels(f.labels, s.labelValues)
+
+			if f.typ == "counter" {
+				fmt.Fprintf(bw, "%s{%s} %s\n", fullName, labels, formatValue(s.value))
+
+				continue
+			}
+
+			for i, bound := range m.buckets() {
+				fmt.Fprintf(bw, "%s_bucket{%s,le=\"%s\"} %d\n", fullName, labels, formatValue(bound), s.buckets[i])
+			}
+
+			fmt.Fprintf(bw, "%s_bucket{%s,le=\"+Inf\"} %d\n", fullName, labels, s.count)
+			fmt.Fprintf(bw, "%s_sum{%s} %s\n", fullName, labels, formatValue(s.value))
+			fmt.Fprintf(bw, "%s_count{%s} %d\n", fullName, labels, s.count)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func formatLabels(names, values []string) string {
+	var sb strings.Builder
+
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+
+		sb.WriteString(name + "=\"" + escapeLabelValue(values[i]) + "\"")
+	}
+
+	return sb.String()
+}
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
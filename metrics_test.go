@@ -0,0 +1,72 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+	"github.com/modern-dev/tgbot-go/tgbottest"
+)
+
+func TestMetricsCountHandlerRunsByResult(t *testing.T) {
+	srv := tgbottest.NewServer()
+	defer srv.Close()
+
+	metrics := tgbot.NewMetrics()
+	bot := srv.NewBot()
+	bot.Instrumentation = metrics
+
+	router := tgbot.NewRouter()
+	router.Use(tgbot.AllowChats(1, 2, 3, 4))
+	router.OnMessage(func(c *tgbot.Context) error {
+		switch c.Chat().Id {
+		case 2:
+			return errors.New("failed")
+		case 3:
+			panic("boom")
+		case 4:
+			return tgbot.ErrNotHandled
+		}
+
+		_, err := c.Reply("hi", nil)
+
+		return err
+	})
+
+	d := tgbot.NewDispatcher(router, 1, 1)
+	d.Instrumentation = metrics
+	d.OnError = func(c *tgbot.Context, err error) {}
+	defer d.Shutdown(context.Background())
+
+	for chatId := int64(1); chatId <= 5; chatId++ {
+		u := &tgbot.Update{Message: &tgbot.Message{Chat: &tgbot.Chat{Id: chatId, Type: "private"}, Text: "hi"}}
+
+		if err := d.DispatchSync(context.Background(), bot, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+
+	if err := metrics.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, result := range []string{"ok", "error", "panic", "not_handled", "forbidden"} {
+		if !strings.Contains(out.String(), `kind="message",result="`+result+`"} 1`) {
+			t.Errorf("no %s handler run in\n%s", result, out.String())
+		}
+	}
+
+	if !strings.Contains(out.String(), `method="sendMessage"`) || strings.Contains(out.String(), tgbottest.Token) {
+		t.Errorf("API calls are not counted without the token in\n%s", out.String())
+	}
+}
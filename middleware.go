@@ -0,0 +1,123 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// ErrForbidden is returned by the AllowIf, AllowUsers and AllowChats middlewares for the updates they
+// refuse. Unlike ErrNotHandled, it stops routing, so that no later route handles the refused update.
+var ErrForbidden = errors.New("tgbot: update not allowed")
+
+// Middleware wraps a handler to run code around it.
+//
+// Middlewares are applied at three levels, from the outermost to the innermost:
+// the middlewares of a router, then of each group the update is routed through, then of the route
+// whose handler processes it. Router middlewares see every update passed to the router, group
+// middlewares see the updates matching the group's filters, and route middlewares see only
+// the updates the route's handler is called for. Within a level, middlewares run in the order
+// they were added, the first added being the outermost.
+type Middleware func(Handler) Handler
+
+// chain wraps h with the middlewares, the first middleware being the outermost.
+func chain(h Handler, mws []Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+
+	return h
+}
+
+// PanicError is returned by the Recover middleware when a handler panics.
+type PanicError struct {
+	// Value passed to panic
+	Value interface{}
+	// Update being handled
+	Update *Update
+	// Stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("tgbot: panic handling %s update %d: %v", e.Update.Kind(), e.Update.UpdateId, e.Value)
+}
+
+// Recover returns a middleware that recovers from panics in the handler and returns them
+// as a *PanicError carrying the offending update.
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(c *Context) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = &PanicError{
+						Value:  r,
+						Update: c.Update,
+						Stack:  debug.Stack(),
+					}
+				}
+			}()
+
+			return next.HandleUpdate(c)
+		})
+	}
+}
+
+// Timing returns a middleware that reports how long the handler took and the error it returned.
+func Timing(report func(c *Context, d time.Duration, err error)) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(c *Context) error {
+			start := time.Now()
+			err := next.HandleUpdate(c)
+			report(c, time.Since(start), err)
+
+			return err
+		})
+	}
+}
+
+// AllowUsers returns a middleware that passes only the updates caused by the users with the identifiers.
+// Other updates are refused with ErrForbidden.
+func AllowUsers(ids ...int) Middleware {
+	return AllowIf(FromUser(ids...))
+}
+
+// AllowChats returns a middleware that passes only the updates from the chats with the identifiers.
+// Other updates are refused with ErrForbidden.
+func AllowChats(ids ...int64) Middleware {
+	return AllowIf(func(c *Context) bool {
+		chat := c.Chat()
+
+		if chat == nil {
+			return false
+		}
+
+		for _, id := range ids {
+			if chat.Id == id {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// AllowIf returns a middleware that passes only the updates matching the filter.
+// Other updates are refused with ErrForbidden.
+func AllowIf(f Filter) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(c *Context) error {
+			if !f(c) {
+				return ErrForbidden
+			}
+
+			return next.HandleUpdate(c)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"context"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+func messageFrom(userId int, chatId int64) *tgbot.Update {
+	return &tgbot.Update{
+		UpdateId: 1,
+		Message: &tgbot.Message{
+			MessageId: 1,
+			From:      &tgbot.User{Id: userId},
+			Chat:      &tgbot.Chat{Id: chatId, Type: "private"},
+		},
+	}
+}
+
+func TestAllowUsersStopsRouting(t *testing.T) {
+	var handled []string
+	record := func(name string) tgbot.HandlerFunc {
+		return func(c *tgbot.Context) error {
+			handled = append(handled, name)
+
+			return nil
+		}
+	}
+
+	router := tgbot.NewRouter()
+	admin := router.Group()
+	admin.Use(tgbot.AllowUsers(1))
+	admin.OnMessage(record("admin"))
+	router.OnMessage(record("catch-all"))
+
+	bot := &tgbot.Bot{Token: "123:abc"}
+
+	if err := router.HandleUpdate(tgbot.NewContext(context.Background(), bot, messageFrom(1, 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	err := router.HandleUpdate(tgbot.NewContext(context.Background(), bot, messageFrom(2, 2)))
+
+	if err != tgbot.ErrForbidden {
+		t.Errorf("HandleUpdate from another user = %v, want ErrForbidden", err)
+	}
+
+	if len(handled) != 1 || handled[0] != "admin" {
+		t.Errorf("handled by %v, want only admin", handled)
+	}
+}
+
+func TestAllowChats(t *testing.T) {
+	bot := &tgbot.Bot{Token: "123:abc"}
+	h := tgbot.AllowChats(10)(tgbot.HandlerFunc(func(c *tgbot.Context) error { return nil }))
+
+	if err := h.HandleUpdate(tgbot.NewContext(context.Background(), bot, messageFrom(1, 10))); err != nil {
+		t.Errorf("update from an allowed chat: %v", err)
+	}
+
+	if err := h.HandleUpdate(tgbot.NewContext(context.Background(), bot, messageFrom(1, 11))); err != tgbot.ErrForbidden {
+		t.Errorf("update from another chat: %v, want ErrForbidden", err)
+	}
+
+	// Updates without a chat are refused.
+	if err := h.HandleUpdate(tgbot.NewContext(context.Background(), bot, &tgbot.Update{UpdateId: 2})); err != tgbot.ErrForbidden {
+		t.Errorf("update without a chat: %v, want ErrForbidden", err)
+	}
+}
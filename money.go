@@ -0,0 +1,222 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// currenciesJson is a snapshot of https://core.telegram.org/bots/payments/currencies.json.
+// The min and max amounts follow exchange rates, so the file should be refreshed from time to time.
+//
+//go:embed currencies.json
+var currenciesJson []byte
+
+var (
+	currencies     map[string]*Currency
+	currenciesOnce sync.Once
+	currenciesErr  error
+)
+
+// Currency describes a currency supported by Telegram Payments.
+type Currency struct {
+	// Three-letter ISO 4217 currency code
+	Code string `json:"code"`
+	// Currency name
+	Title string `json:"title"`
+	// Currency symbol
+	Symbol string `json:"symbol"`
+	// Number of digits past the decimal point
+	Exp int `json:"exp"`
+	// Minimum amount of a payment in the smallest units of the currency
+	MinAmount int64 `json:"min_amount,string"`
+	// Maximum amount of a payment in the smallest units of the currency
+	MaxAmount int64 `json:"max_amount,string"`
+}
+
+// LookupCurrency returns the currency with the ISO 4217 code.
+func LookupCurrency(code string) (*Currency, error) {
+	currenciesOnce.Do(func() {
+		currenciesErr = json.Unmarshal(currenciesJson, &currencies)
+	})
+
+	if currenciesErr != nil {
+		return nil, currenciesErr
+	}
+
+	currency, ok := currencies[strings.ToUpper(code)]
+
+	if !ok {
+		return nil, fmt.Errorf("tgbot: unsupported currency %q", code)
+	}
+
+	return currency, nil
+}
+
+// Money is an amount in the smallest units of a currency, as used by invoices and payments.
+// For example, US$ 1.45 is Money{Amount: 145, Currency: "USD"}.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// ParseMoney parses a decimal string such as "1.45" into Money of the currency.
+// The string must not have more digits past the decimal point than the currency allows.
+func ParseMoney(s, currency string) (Money, error) {
+	c, err := LookupCurrency(currency)
+
+	if err != nil {
+		return Money{}, err
+	}
+
+	digits := strings.TrimSpace(s)
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+	whole, frac := digits, ""
+
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		whole, frac = digits[:i], digits[i+1:]
+	}
+
+	if whole == "" || len(frac) > c.Exp || strings.IndexByte(frac, '.') >= 0 || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("tgbot: invalid %s amount %q", c.Code, s)
+	}
+
+	amount, err := strconv.ParseInt(whole+frac+strings.Repeat("0", c.Exp-len(frac)), 10, 64)
+
+	if err != nil {
+		return Money{}, fmt.Errorf("tgbot: invalid %s amount %q", c.Code, s)
+	}
+
+	if negative {
+		amount = -amount
+	}
+
+	return Money{Amount: amount, Currency: c.Code}, nil
+}
+
+// Decimal returns the amount as a decimal string, e.g. "1.45" for 145 US cents.
+func (m Money) Decimal() (string, error) {
+	c, err := LookupCurrency(m.Currency)
+
+	if err != nil {
+		return "", err
+	}
+
+	// The magnitude is unsigned so that the smallest int64 can be negated.
+	amount := uint64(m.Amount)
+	sign := ""
+
+	if m.Amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.FormatUint(amount, 10)
+
+	if c.Exp == 0 {
+		return sign + digits, nil
+	}
+
+	if len(digits) <= c.Exp {
+		digits = strings.Repeat("0", c.Exp-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-c.Exp] + "." + digits[len(digits)-c.Exp:], nil
+}
+
+// String returns the amount followed by the currency code, e.g. "1.45 USD".
+func (m Money) String() string {
+	decimal, err := m.Decimal()
+
+	if err != nil {
+		return strconv.FormatInt(m.Amount, 10) + " " + m.Currency
+	}
+
+	return decimal + " " + m.Currency
+}
+
+// Add returns the sum of two amounts of the same currency. It returns an error if the sum
+// does not fit in an int64.
+func (m Money) Add(o Money) (Money, error) {
+	if !strings.EqualFold(m.Currency, o.Currency) {
+		return Money{}, fmt.Errorf("tgbot: cannot add %s to %s", o.Currency, m.Currency)
+	}
+
+	sum := m.Amount + o.Amount
+
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, fmt.Errorf("tgbot: sum of %s and %s overflows", m, o)
+	}
+
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Validate checks that the currency is supported and that the amount is within the limits
+// Telegram accepts for a payment.
+func (m Money) Validate() error {
+	c, err := LookupCurrency(m.Currency)
+
+	if err != nil {
+		return err
+	}
+
+	if m.Amount < c.MinAmount || m.Amount > c.MaxAmount {
+		return fmt.Errorf("tgbot: amount %s is out of range [%s, %s]",
+			m, Money{c.MinAmount, c.Code}, Money{c.MaxAmount, c.Code})
+	}
+
+	return nil
+}
+
+// ValidateInvoice checks that the total of the prices is a valid payment amount in the currency.
+func ValidateInvoice(currency string, prices []LabeledPrice) error {
+	if len(prices) == 0 {
+		return fmt.Errorf("tgbot: invoice has no prices")
+	}
+
+	total := Money{Currency: currency}
+
+	for _, price := range prices {
+		var err error
+
+		if total, err = total.Add(Money{Amount: int64(price.Amount), Currency: currency}); err != nil {
+			return err
+		}
+	}
+
+	return total.Validate()
+}
+
+// Total returns the total price of the invoice.
+func (i *Invoice) Total() Money {
+	return Money{Amount: int64(i.TotalAmount), Currency: i.Currency}
+}
+
+// Total returns the total price of the payment.
+func (p *SuccessfulPayment) Total() Money {
+	return Money{Amount: int64(p.TotalAmount), Currency: p.Currency}
+}
+
+// Total returns the total price of the order.
+func (q *PreCheckoutQuery) Total() Money {
+	return Money{Amount: int64(q.TotalAmount), Currency: q.Currency}
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
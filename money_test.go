@@ -0,0 +1,134 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"math"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+func init() {
+	tgbot.AddCurrency(&tgbot.Currency{Code: "KWD", Title: "Kuwaiti Dinar", Exp: 3, MinAmount: 300, MaxAmount: 3000000})
+}
+
+func TestParseMoney(t *testing.T) {
+	for _, tc := range []struct {
+		s, currency string
+		want        int64
+	}{
+		{"1.45", "USD", 145},
+		{"1.4", "USD", 140},
+		{"1", "usd", 100},
+		{" 0.05 ", "USD", 5},
+		{"-1.45", "USD", -145},
+		{"1500", "JPY", 1500},
+		{"-7", "JPY", -7},
+		{"1.234", "KWD", 1234},
+		{"0.5", "KWD", 500},
+		{"92233720368547758.07", "USD", math.MaxInt64},
+	} {
+		m, err := tgbot.ParseMoney(tc.s, tc.currency)
+
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %s): %v", tc.s, tc.currency, err)
+
+			continue
+		}
+
+		if m.Amount != tc.want {
+			t.Errorf("ParseMoney(%q, %s) = %d, want %d", tc.s, tc.currency, m.Amount, tc.want)
+		}
+	}
+}
+
+func TestParseMoneyRejectsInvalidAmounts(t *testing.T) {
+	for _, tc := range []struct{ s, currency string }{
+		{"", "USD"},
+		{"-", "USD"},
+		{".5", "USD"},
+		{"1.455", "USD"}, // amounts are not rounded
+		{"1.5", "JPY"},
+		{"1.2345", "KWD"},
+		{"1.2.3", "USD"},
+		{"1,45", "USD"},
+		{"+1", "USD"},
+		{"--1", "USD"},
+		{"1e3", "USD"},
+		{"92233720368547758.08", "USD"},
+		{"1", "XXX"},
+	} {
+		if m, err := tgbot.ParseMoney(tc.s, tc.currency); err == nil {
+			t.Errorf("ParseMoney(%q, %s) = %v, want an error", tc.s, tc.currency, m)
+		}
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	for _, tc := range []struct {
+		m    tgbot.Money
+		want string
+	}{
+		{tgbot.Money{Amount: 145, Currency: "USD"}, "1.45"},
+		{tgbot.Money{Amount: 5, Currency: "USD"}, "0.05"},
+		{tgbot.Money{Amount: 0, Currency: "USD"}, "0.00"},
+		{tgbot.Money{Amount: -145, Currency: "USD"}, "-1.45"},
+		{tgbot.Money{Amount: 1500, Currency: "JPY"}, "1500"},
+		{tgbot.Money{Amount: -7, Currency: "JPY"}, "-7"},
+		{tgbot.Money{Amount: 1234, Currency: "KWD"}, "1.234"},
+		{tgbot.Money{Amount: 1, Currency: "KWD"}, "0.001"},
+		{tgbot.Money{Amount: math.MinInt64, Currency: "USD"}, "-92233720368547758.08"},
+	} {
+		got, err := tc.m.Decimal()
+
+		if err != nil {
+			t.Errorf("Decimal of %d %s: %v", tc.m.Amount, tc.m.Currency, err)
+		} else if got != tc.want {
+			t.Errorf("Decimal of %d %s = %q, want %q", tc.m.Amount, tc.m.Currency, got, tc.want)
+		}
+
+		if parsed, err := tgbot.ParseMoney(got, tc.m.Currency); err == nil && parsed.Amount != tc.m.Amount {
+			t.Errorf("ParseMoney(%q) = %d, want %d", got, parsed.Amount, tc.m.Amount)
+		}
+	}
+}
+
+func TestMoneyAdd(t *testing.T) {
+	sum, err := tgbot.Money{Amount: 145, Currency: "USD"}.Add(tgbot.Money{Amount: -45, Currency: "usd"})
+
+	if err != nil || sum != (tgbot.Money{Amount: 100, Currency: "USD"}) {
+		t.Errorf("Add = %v, %v, want 1.00 USD", sum, err)
+	}
+
+	for _, tc := range []struct{ a, b tgbot.Money }{
+		{tgbot.Money{Amount: 1, Currency: "USD"}, tgbot.Money{Amount: 1, Currency: "EUR"}},
+		{tgbot.Money{Amount: math.MaxInt64, Currency: "USD"}, tgbot.Money{Amount: 1, Currency: "USD"}},
+		{tgbot.Money{Amount: math.MinInt64, Currency: "USD"}, tgbot.Money{Amount: -1, Currency: "USD"}},
+	} {
+		if sum, err := tc.a.Add(tc.b); err == nil {
+			t.Errorf("%d %s + %d %s = %v, want an error", tc.a.Amount, tc.a.Currency, tc.b.Amount, tc.b.Currency, sum)
+		}
+	}
+}
+
+func TestValidateInvoice(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		prices []tgbot.LabeledPrice
+		valid  bool
+	}{
+		{"within limits", []tgbot.LabeledPrice{{Label: "Item", Amount: 1000}, {Label: "Discount", Amount: -200}}, true},
+		{"below the minimum", []tgbot.LabeledPrice{{Label: "Item", Amount: 99}}, false},
+		{"above the maximum", []tgbot.LabeledPrice{{Label: "Item", Amount: 1000001}}, false},
+		{"no prices", nil, false},
+		{"overflow", []tgbot.LabeledPrice{{Label: "A", Amount: math.MaxInt}, {Label: "B", Amount: math.MaxInt}}, false},
+	} {
+		if err := tgbot.ValidateInvoice("USD", tc.prices); (err == nil) != tc.valid {
+			t.Errorf("%s: ValidateInvoice = %v", tc.name, err)
+		}
+	}
+}
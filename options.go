@@ -5,16 +5,28 @@
 
 package tgbot
 
-import "strconv"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
 
 type SendMessageOptions struct {
+	// ParseMode is “MarkdownV2”, “HTML” or “Markdown” to format the text with markup.
+	ParseMode string
+	// Entities formats the text instead of ParseMode, e.g. the entities made by a TextBuilder.
+	Entities              []MessageEntity
 	DisableWebPagePreview bool
 	DisableNotification   bool
 	ReplyToMessageId      int
 	ReplyMarkup           string
 }
 
-func (smo *SendMessageOptions) addOptions(params map[string]string) {
+func (smo *SendMessageOptions) addOptions(params map[string]string) error {
+	if err := addFormatting(params, "entities", smo.ParseMode, smo.Entities); err != nil {
+		return err
+	}
+
 	if smo.DisableWebPagePreview {
 		params["disable_web_page_preview"] = "true"
 	}
@@ -30,4 +42,328 @@ func (smo *SendMessageOptions) addOptions(params map[string]string) {
 	if smo.ReplyMarkup != "" {
 		params["reply_markup"] = smo.ReplyMarkup
 	}
+
+	return nil
+}
+
+// addFormatting adds the parse mode or the entities, passed as the parameter with the name, of a text
+// or a caption.
+func addFormatting(params map[string]string, name, parseMode string, entities []MessageEntity) error {
+	if parseMode != "" && len(entities) > 0 {
+		return fmt.Errorf("tgbot: both a parse mode and %s are specified", name)
+	}
+
+	if parseMode != "" {
+		params["parse_mode"] = parseMode
+	}
+
+	if len(entities) > 0 {
+		data, err := json.Marshal(entities)
+
+		if err != nil {
+			return err
+		}
+
+		params[name] = string(data)
+	}
+
+	return nil
+}
+
+type SendPhotoOptions struct {
+	// Caption of the photo, 0-1024 characters
+	Caption string
+	// ParseMode is “MarkdownV2”, “HTML” or “Markdown” to format the caption with markup.
+	ParseMode string
+	// CaptionEntities formats the caption instead of ParseMode.
+	CaptionEntities     []MessageEntity
+	DisableNotification bool
+	ReplyToMessageId    int
+	ReplyMarkup         string
+}
+
+func (spo *SendPhotoOptions) addOptions(params map[string]string) error {
+	if spo.Caption != "" {
+		params["caption"] = spo.Caption
+	}
+
+	if err := addFormatting(params, "caption_entities", spo.ParseMode, spo.CaptionEntities); err != nil {
+		return err
+	}
+
+	if spo.DisableNotification {
+		params["disable_notification"] = "true"
+	}
+
+	if spo.ReplyToMessageId != 0 {
+		params["reply_to_message_id"] = strconv.Itoa(spo.ReplyToMessageId)
+	}
+
+	if spo.ReplyMarkup != "" {
+		params["reply_markup"] = spo.ReplyMarkup
+	}
+
+	return nil
+}
+
+type EditMessageTextOptions struct {
+	// ParseMode is “MarkdownV2”, “HTML” or “Markdown” to format the text with markup.
+	ParseMode string
+	// Entities formats the text instead of ParseMode.
+	Entities              []MessageEntity
+	DisableWebPagePreview bool
+	ReplyMarkup           string
+}
+
+func (emo *EditMessageTextOptions) addOptions(params map[string]string) error {
+	if err := addFormatting(params, "entities", emo.ParseMode, emo.Entities); err != nil {
+		return err
+	}
+
+	if emo.DisableWebPagePreview {
+		params["disable_web_page_preview"] = "true"
+	}
+
+	if emo.ReplyMarkup != "" {
+		params["reply_markup"] = emo.ReplyMarkup
+	}
+
+	return nil
+}
+
+type SendStickerOptions struct {
+	DisableNotification bool
+	ReplyToMessageId    int
+	ReplyMarkup         string
+}
+
+func (sso *SendStickerOptions) addOptions(params map[string]string) {
+	if sso.DisableNotification {
+		params["disable_notification"] = "true"
+	}
+
+	if sso.ReplyToMessageId != 0 {
+		params["reply_to_message_id"] = strconv.Itoa(sso.ReplyToMessageId)
+	}
+
+	if sso.ReplyMarkup != "" {
+		params["reply_markup"] = sso.ReplyMarkup
+	}
+}
+
+type SendInvoiceOptions struct {
+	ProviderData              string
+	PhotoUrl                  string
+	PhotoSize                 int
+	PhotoWidth                int
+	PhotoHeight               int
+	NeedName                  bool
+	NeedPhoneNumber           bool
+	NeedEmail                 bool
+	NeedShippingAddress       bool
+	SendPhoneNumberToProvider bool
+	SendEmailToProvider       bool
+	IsFlexible                bool
+	DisableNotification       bool
+	ReplyToMessageId          int
+	ReplyMarkup               string
+}
+
+func (sio *SendInvoiceOptions) addOptions(params map[string]string) {
+	if sio.ProviderData != "" {
+		params["provider_data"] = sio.ProviderData
+	}
+
+	if sio.PhotoUrl != "" {
+		params["photo_url"] = sio.PhotoUrl
+	}
+
+	if sio.PhotoSize != 0 {
+		params["photo_size"] = strconv.Itoa(sio.PhotoSize)
+	}
+
+	if sio.PhotoWidth != 0 {
+		params["photo_width"] = strconv.Itoa(sio.PhotoWidth)
+	}
+
+	if sio.PhotoHeight != 0 {
+		params["photo_height"] = strconv.Itoa(sio.PhotoHeight)
+	}
+
+	if sio.NeedName {
+		params["need_name"] = "true"
+	}
+
+	if sio.NeedPhoneNumber {
+		params["need_phone_number"] = "true"
+	}
+
+	if sio.NeedEmail {
+		params["need_email"] = "true"
+	}
+
+	if sio.NeedShippingAddress {
+		params["need_shipping_address"] = "true"
+	}
+
+	if sio.SendPhoneNumberToProvider {
+		params["send_phone_number_to_provider"] = "true"
+	}
+
+	if sio.SendEmailToProvider {
+		params["send_email_to_provider"] = "true"
+	}
+
+	if sio.IsFlexible {
+		params["is_flexible"] = "true"
+	}
+
+	if sio.DisableNotification {
+		params["disable_notification"] = "true"
+	}
+
+	if sio.ReplyToMessageId != 0 {
+		params["reply_to_message_id"] = strconv.Itoa(sio.ReplyToMessageId)
+	}
+
+	if sio.ReplyMarkup != "" {
+		params["reply_markup"] = sio.ReplyMarkup
+	}
+}
+
+// MessageTarget identifies a message either by chat and message identifiers or, for messages
+// sent via the bot in inline mode, by the inline message identifier.
+type MessageTarget struct {
+	ChatId          string
+	MessageId       int
+	InlineMessageId string
+}
+
+// ChatMessage returns the target of the message with messageId in the chat.
+func ChatMessage(chatId string, messageId int) MessageTarget {
+	return MessageTarget{ChatId: chatId, MessageId: messageId}
+}
+
+// InlineMessage returns the target of the inline message.
+func InlineMessage(inlineMessageId string) MessageTarget {
+	return MessageTarget{InlineMessageId: inlineMessageId}
+}
+
+// IsInline reports whether the target is an inline message.
+func (mt *MessageTarget) IsInline() bool {
+	return mt.InlineMessageId != ""
+}
+
+func (mt *MessageTarget) addOptions(params map[string]string) error {
+	if mt.IsInline() {
+		if mt.ChatId != "" || mt.MessageId != 0 {
+			return fmt.Errorf("tgbot: message target must be either a chat message or an inline message")
+		}
+
+		params["inline_message_id"] = mt.InlineMessageId
+
+		return nil
+	}
+
+	if mt.ChatId == "" || mt.MessageId == 0 {
+		return fmt.Errorf("tgbot: message target requires both chat and message identifiers")
+	}
+
+	params["chat_id"] = mt.ChatId
+	params["message_id"] = strconv.Itoa(mt.MessageId)
+
+	return nil
+}
+
+type SendGameOptions struct {
+	DisableNotification bool
+	ReplyToMessageId    int
+	ReplyMarkup         string
+}
+
+func (sgo *SendGameOptions) addOptions(params map[string]string) {
+	if sgo.DisableNotification {
+		params["disable_notification"] = "true"
+	}
+
+	if sgo.ReplyToMessageId != 0 {
+		params["reply_to_message_id"] = strconv.Itoa(sgo.ReplyToMessageId)
+	}
+
+	if sgo.ReplyMarkup != "" {
+		params["reply_markup"] = sgo.ReplyMarkup
+	}
+}
+
+type SetGameScoreOptions struct {
+	Force              bool
+	DisableEditMessage bool
+}
+
+func (sgso *SetGameScoreOptions) addOptions(params map[string]string) {
+	if sgso.Force {
+		params["force"] = "true"
+	}
+
+	if sgso.DisableEditMessage {
+		params["disable_edit_message"] = "true"
+	}
+}
+
+type AnswerCallbackQueryOptions struct {
+	Text      string
+	ShowAlert bool
+	Url       string
+	CacheTime int
+}
+
+func (acqo *AnswerCallbackQueryOptions) addOptions(params map[string]string) {
+	if acqo.Text != "" {
+		params["text"] = acqo.Text
+	}
+
+	if acqo.ShowAlert {
+		params["show_alert"] = "true"
+	}
+
+	if acqo.Url != "" {
+		params["url"] = acqo.Url
+	}
+
+	if acqo.CacheTime != 0 {
+		params["cache_time"] = strconv.Itoa(acqo.CacheTime)
+	}
+}
+
+type GetUpdatesOptions struct {
+	Offset         int
+	Limit          int
+	Timeout        int
+	AllowedUpdates []string
+}
+
+func (guo *GetUpdatesOptions) addOptions(params map[string]string) error {
+	if guo.Offset != 0 {
+		params["offset"] = strconv.Itoa(guo.Offset)
+	}
+
+	if guo.Limit != 0 {
+		params["limit"] = strconv.Itoa(guo.Limit)
+	}
+
+	if guo.Timeout != 0 {
+		params["timeout"] = strconv.Itoa(guo.Timeout)
+	}
+
+	if guo.AllowedUpdates != nil {
+		allowed, err := json.Marshal(guo.AllowedUpdates)
+
+		if err != nil {
+			return err
+		}
+
+		params["allowed_updates"] = string(allowed)
+	}
+
+	return nil
 }
@@ -0,0 +1,590 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+)
+
+// Credentials contains the data required to decrypt the Telegram Passport elements shared with the bot.
+type Credentials struct {
+	// Credentials for encrypted data
+	SecureData SecureData `json:"secure_data"`
+	// Bot-specified nonce
+	Nonce string `json:"nonce"`
+}
+
+// SecureData represents the credentials required to decrypt encrypted data.
+// All fields are optional and depend on fields that were requested.
+type SecureData struct {
+	PersonalDetails       *SecureValue `json:"personal_details,omitempty"`
+	Passport              *SecureValue `json:"passport,omitempty"`
+	InternalPassport      *SecureValue `json:"internal_passport,omitempty"`
+	DriverLicense         *SecureValue `json:"driver_license,omitempty"`
+	IdentityCard          *SecureValue `json:"identity_card,omitempty"`
+	Address               *SecureValue `json:"address,omitempty"`
+	UtilityBill           *SecureValue `json:"utility_bill,omitempty"`
+	BankStatement         *SecureValue `json:"bank_statement,omitempty"`
+	RentalAgreement       *SecureValue `json:"rental_agreement,omitempty"`
+	PassportRegistration  *SecureValue `json:"passport_registration,omitempty"`
+	TemporaryRegistration *SecureValue `json:"temporary_registration,omitempty"`
+}
+
+// Value returns the credentials of the element type, or nil if the element was not shared.
+func (sd *SecureData) Value(elementType string) *SecureValue {
+	switch elementType {
+	case "personal_details":
+		return sd.PersonalDetails
+	case "passport":
+		return sd.Passport
+	case "internal_passport":
+		return sd.InternalPassport
+	case "driver_license":
+		return sd.DriverLicense
+	case "identity_card":
+		return sd.IdentityCard
+	case "address":
+		return sd.Address
+	case "utility_bill":
+		return sd.UtilityBill
+	case "bank_statement":
+		return sd.BankStatement
+	case "rental_agreement":
+		return sd.RentalAgreement
+	case "passport_registration":
+		return sd.PassportRegistration
+	case "temporary_registration":
+		return sd.TemporaryRegistration
+	}
+
+	return nil
+}
+
+// SecureValue represents the credentials required to decrypt encrypted values.
+// All fields are optional and depend on the type of fields that were requested.
+type SecureValue struct {
+	// Credentials for encrypted Telegram Passport data
+	Data *DataCredentials `json:"data,omitempty"`
+	// Credentials for an encrypted document's front side
+	FrontSide *FileCredentials `json:"front_side,omitempty"`
+	// Credentials for an encrypted document's reverse side
+	ReverseSide *FileCredentials `json:"reverse_side,omitempty"`
+	// Credentials for an encrypted selfie of the user with a document
+	Selfie *FileCredentials `json:"selfie,omitempty"`
+	// Credentials for an encrypted translation of the document
+	Translation []FileCredentials `json:"translation,omitempty"`
+	// Credentials for encrypted files
+	Files []FileCredentials `json:"files,omitempty"`
+}
+
+// DataCredentials can be used to decrypt encrypted data from the data field in EncryptedPassportElement.
+type DataCredentials struct {
+	// Checksum of encrypted data
+	DataHash string `json:"data_hash"`
+	// Secret of encrypted data
+	Secret string `json:"secret"`
+}
+
+// FileCredentials can be used to decrypt encrypted files from the front_side, reverse_side, selfie,
+// files and translation fields in EncryptedPassportElement.
+type FileCredentials struct {
+	// Checksum of encrypted file
+	FileHash string `json:"file_hash"`
+	// Secret of encrypted file
+	Secret string `json:"secret"`
+}
+
+// PersonalDetails represents personal details.
+type PersonalDetails struct {
+	FirstName            string `json:"first_name"`
+	LastName             string `json:"last_name"`
+	MiddleName           string `json:"middle_name,omitempty"`
+	BirthDate            string `json:"birth_date"`
+	Gender               string `json:"gender"`
+	CountryCode          string `json:"country_code"`
+	ResidenceCountryCode string `json:"residence_country_code"`
+	FirstNameNative      string `json:"first_name_native"`
+	LastNameNative       string `json:"last_name_native"`
+	MiddleNameNative     string `json:"middle_name_native,omitempty"`
+}
+
+// ResidentialAddress represents a residential address.
+type ResidentialAddress struct {
+	StreetLine1 string `json:"street_line1"`
+	StreetLine2 string `json:"street_line2,omitempty"`
+	City        string `json:"city"`
+	State       string `json:"state,omitempty"`
+	CountryCode string `json:"country_code"`
+	PostCode    string `json:"post_code"`
+}
+
+// IdDocumentData represents the data of an identity document.
+type IdDocumentData struct {
+	DocumentNo string `json:"document_no"`
+	ExpiryDate string `json:"expiry_date,omitempty"`
+}
+
+// PassportDecryptor decrypts Telegram Passport data with the bot's private RSA key.
+type PassportDecryptor struct {
+	key *rsa.PrivateKey
+}
+
+// NewPassportDecryptor returns a decryptor for the PEM-encoded PKCS #1 or PKCS #8 private key.
+func NewPassportDecryptor(pemKey []byte) (*PassportDecryptor, error) {
+	block, _ := pem.Decode(pemKey)
+
+	if block == nil {
+		return nil, fmt.Errorf("tgbot: no PEM data in private key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return &PassportDecryptor{key: key}, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+
+	if !ok {
+		return nil, fmt.Errorf("tgbot: passport key is not an RSA key")
+	}
+
+	return &PassportDecryptor{key: rsaKey}, nil
+}
+
+// DecryptCredentials decrypts the credentials secret with the private key and then the credentials.
+func (d *PassportDecryptor) DecryptCredentials(ec *EncryptedCredentials) (*Credentials, error) {
+	encryptedSecret, err := base64.StdEncoding.DecodeString(ec.Secret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := rsa.DecryptOAEP(sha1.New(), nil, d.key, encryptedSecret, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("tgbot: cannot decrypt passport credentials secret: %v", err)
+	}
+
+	hash, err := base64.StdEncoding.DecodeString(ec.Hash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decryptPassportValue(ec.Data, secret, hash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var creds Credentials
+
+	if err = json.Unmarshal(data, &creds); err != nil {
+		return nil, err
+	}
+
+	return &creds, nil
+}
+
+// DecryptData decrypts the data field of the element and unmarshals it into v, which is usually
+// a PersonalDetails, a ResidentialAddress or an IdDocumentData.
+func (d *PassportDecryptor) DecryptData(el *EncryptedPassportElement, dc *DataCredentials, v interface{}) error {
+	if el.Data == "" {
+		return fmt.Errorf("tgbot: passport element %s has no data", el.Type)
+	}
+
+	secret, hash, err := decodeSecretAndHash(dc.Secret, dc.DataHash)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := decryptPassportValue(el.Data, secret, hash)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// DecryptFile decrypts the contents of a downloaded passport file.
+func (d *PassportDecryptor) DecryptFile(fc *FileCredentials, encrypted []byte) ([]byte, error) {
+	secret, hash, err := decodeSecretAndHash(fc.Secret, fc.FileHash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptPassport(encrypted, secret, hash)
+}
+
+// DecryptedPassportElement is a Telegram Passport element with its data and files decrypted.
+type DecryptedPassportElement struct {
+	// Element type
+	Type string
+	// Decrypted JSON-serialized data, if the element has data
+	Data json.RawMessage
+	// User's verified phone number, for “phone_number” type
+	PhoneNumber string
+	// User's verified email address, for “email” type
+	Email string
+	// Decrypted front side of the document
+	FrontSide []byte
+	// Decrypted reverse side of the document
+	ReverseSide []byte
+	// Decrypted selfie of the user holding the document
+	Selfie []byte
+	// Decrypted files with documents
+	Files [][]byte
+	// Decrypted translations of the document
+	Translation [][]byte
+	// Element hash for using in PassportElementErrorUnspecified
+	Hash string
+}
+
+// Decrypt decrypts the credentials and every element of the passport data, downloading element files
+// with the bot. It returns an error if the data or a file of an element is not covered by the credentials,
+// as the credentials then do not match the data.
+func (d *PassportDecryptor) Decrypt(bot *Bot, pd *PassportData) (*Credentials, []DecryptedPassportElement, error) {
+	creds, err := d.DecryptCredentials(pd.Credentials)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	elements := make([]DecryptedPassportElement, 0, len(pd.Data))
+
+	for i := range pd.Data {
+		el := &pd.Data[i]
+		dec := DecryptedPassportElement{
+			Type:        el.Type,
+			PhoneNumber: el.PhoneNumber,
+			Email:       el.Email,
+			Hash:        el.Hash,
+		}
+
+		if el.Type == "phone_number" || el.Type == "email" {
+			elements = append(elements, dec)
+			continue
+		}
+
+		value := creds.SecureData.Value(el.Type)
+
+		if value == nil {
+			return nil, nil, fmt.Errorf("tgbot: no credentials for passport element %s", el.Type)
+		}
+
+		if el.Data != "" {
+			if value.Data == nil {
+				return nil, nil, fmt.Errorf("tgbot: no credentials for the data of passport element %s", el.Type)
+			}
+
+			if err = d.DecryptData(el, value.Data, &dec.Data); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if dec.FrontSide, err = d.fetchFile(bot, el, el.FrontSide, value.FrontSide); err != nil {
+			return nil, nil, err
+		}
+
+		if dec.ReverseSide, err = d.fetchFile(bot, el, el.ReverseSide, value.ReverseSide); err != nil {
+			return nil, nil, err
+		}
+
+		if dec.Selfie, err = d.fetchFile(bot, el, el.Selfie, value.Selfie); err != nil {
+			return nil, nil, err
+		}
+
+		if dec.Files, err = d.fetchFiles(bot, el, el.Files, value.Files); err != nil {
+			return nil, nil, err
+		}
+
+		if dec.Translation, err = d.fetchFiles(bot, el, el.Translation, value.Translation); err != nil {
+			return nil, nil, err
+		}
+
+		elements = append(elements, dec)
+	}
+
+	return creds, elements, nil
+}
+
+func (d *PassportDecryptor) fetchFile(bot *Bot, el *EncryptedPassportElement, file *PassportFile,
+	fc *FileCredentials) ([]byte, error) {
+	if file == nil {
+		return nil, nil
+	}
+
+	if fc == nil {
+		return nil, fmt.Errorf("tgbot: no credentials for file %s of passport element %s", file.FileId, el.Type)
+	}
+
+	f, err := bot.GetFile(file.FileId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := bot.DownloadFile(f.FilePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return d.DecryptFile(fc, encrypted)
+}
+
+func (d *PassportDecryptor) fetchFiles(bot *Bot, el *EncryptedPassportElement, files []PassportFile,
+	fcs []FileCredentials) ([][]byte, error) {
+	if len(files) != len(fcs) {
+		return nil, fmt.Errorf("tgbot: got credentials for %d of %d files of passport element %s",
+			len(fcs), len(files), el.Type)
+	}
+
+	var decrypted [][]byte
+
+	for i := range files {
+		data, err := d.fetchFile(bot, el, &files[i], &fcs[i])
+
+		if err != nil {
+			return nil, err
+		}
+
+		decrypted = append(decrypted, data)
+	}
+
+	return decrypted, nil
+}
+
+func decodeSecretAndHash(secret, hash string) ([]byte, []byte, error) {
+	s, err := base64.StdEncoding.DecodeString(secret)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h, err := base64.StdEncoding.DecodeString(hash)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s, h, nil
+}
+
+func decryptPassportValue(value string, secret, hash []byte) ([]byte, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptPassport(encrypted, secret, hash)
+}
+
+// decryptPassport decrypts data with AES-256-CBC using the key and IV derived from the secret and
+// the hash, verifies the hash of the decrypted data and strips its random padding.
+func decryptPassport(encrypted, secret, hash []byte) ([]byte, error) {
+	if len(encrypted) == 0 || len(encrypted)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("tgbot: invalid passport data length %d", len(encrypted))
+	}
+
+	secretHash := sha512.Sum512(append(append([]byte{}, secret...), hash...))
+	block, err := aes.NewCipher(secretHash[:32])
+
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, secretHash[32:48]).CryptBlocks(data, encrypted)
+
+	if sum := sha256.Sum256(data); !bytes.Equal(sum[:], hash) {
+		return nil, fmt.Errorf("tgbot: passport data hash mismatch")
+	}
+
+	padding := int(data[0])
+
+	if padding < 32 || padding > len(data) {
+		return nil, fmt.Errorf("tgbot: invalid passport data padding")
+	}
+
+	return data[padding:], nil
+}
+
+// PassportElementError represents an error in the Telegram Passport element which was submitted
+// that should be resolved by the user.
+type PassportElementError interface {
+	passportErrorSource() string
+}
+
+// PassportElementErrorDataField represents an issue in one of the data fields that was provided by the user.
+// The error is considered resolved when the field's value changes.
+type PassportElementErrorDataField struct {
+	// The section of the user's Telegram Passport which has the error, one of “personal_details”,
+	// “passport”, “driver_license”, “identity_card”, “internal_passport”, “address”
+	Type string `json:"type"`
+	// Name of the data field which has the error
+	FieldName string `json:"field_name"`
+	// Base64-encoded data hash
+	DataHash string `json:"data_hash"`
+	// Error message
+	Message string `json:"message"`
+}
+
+// PassportElementErrorFrontSide represents an issue with the front side of a document.
+// The error is considered resolved when the file with the front side of the document changes.
+type PassportElementErrorFrontSide struct {
+	// The section of the user's Telegram Passport which has the issue, one of “passport”, “driver_license”,
+	// “identity_card”, “internal_passport”
+	Type string `json:"type"`
+	// Base64-encoded hash of the file with the front side of the document
+	FileHash string `json:"file_hash"`
+	// Error message
+	Message string `json:"message"`
+}
+
+// PassportElementErrorReverseSide represents an issue with the reverse side of a document.
+// The error is considered resolved when the file with reverse side of the document changes.
+type PassportElementErrorReverseSide struct {
+	// The section of the user's Telegram Passport which has the issue, one of “driver_license”, “identity_card”
+	Type string `json:"type"`
+	// Base64-encoded hash of the file with the reverse side of the document
+	FileHash string `json:"file_hash"`
+	// Error message
+	Message string `json:"message"`
+}
+
+// PassportElementErrorSelfie represents an issue with the selfie with a document.
+// The error is considered resolved when the file with the selfie changes.
+type PassportElementErrorSelfie struct {
+	// The section of the user's Telegram Passport which has the issue, one of “passport”, “driver_license”,
+	// “identity_card”, “internal_passport”
+	Type string `json:"type"`
+	// Base64-encoded hash of the file with the selfie
+	FileHash string `json:"file_hash"`
+	// Error message
+	Message string `json:"message"`
+}
+
+// PassportElementErrorFile represents an issue with a document scan.
+// The error is considered resolved when the file with the document scan changes.
+type PassportElementErrorFile struct {
+	// The section of the user's Telegram Passport which has the issue, one of “utility_bill”, “bank_statement”,
+	// “rental_agreement”, “passport_registration”, “temporary_registration”
+	Type string `json:"type"`
+	// Base64-encoded file hash
+	FileHash string `json:"file_hash"`
+	// Error message
+	Message string `json:"message"`
+}
+
+// PassportElementErrorFiles represents an issue with a list of scans.
+// The error is considered resolved when the list of files containing the scans changes.
+type PassportElementErrorFiles struct {
+	// The section of the user's Telegram Passport which has the issue, one of “utility_bill”, “bank_statement”,
+	// “rental_agreement”, “passport_registration”, “temporary_registration”
+	Type string `json:"type"`
+	// List of base64-encoded file hashes
+	FileHashes []string `json:"file_hashes"`
+	// Error message
+	Message string `json:"message"`
+}
+
+// PassportElementErrorTranslationFile represents an issue with one of the files that constitute
+// the translation of a document. The error is considered resolved when the file changes.
+type PassportElementErrorTranslationFile struct {
+	// Type of element of the user's Telegram Passport which has the issue, one of “passport”,
+	// “driver_license”, “identity_card”, “internal_passport”, “utility_bill”, “bank_statement”,
+	// “rental_agreement”, “passport_registration”, “temporary_registration”
+	Type string `json:"type"`
+	// Base64-encoded file hash
+	FileHash string `json:"file_hash"`
+	// Error message
+	Message string `json:"message"`
+}
+
+// PassportElementErrorTranslationFiles represents an issue with the translated version of a document.
+// The error is considered resolved when a file with the document translation change.
+type PassportElementErrorTranslationFiles struct {
+	// Type of element of the user's Telegram Passport which has the issue, one of “passport”,
+	// “driver_license”, “identity_card”, “internal_passport”, “utility_bill”, “bank_statement”,
+	// “rental_agreement”, “passport_registration”, “temporary_registration”
+	Type string `json:"type"`
+	// List of base64-encoded file hashes
+	FileHashes []string `json:"file_hashes"`
+	// Error message
+	Message string `json:"message"`
+}
+
+// PassportElementErrorUnspecified represents an issue in an unspecified place.
+// The error is considered resolved when new data is added.
+type PassportElementErrorUnspecified struct {
+	// Type of element of the user's Telegram Passport which has the issue
+	Type string `json:"type"`
+	// Base64-encoded element hash
+	ElementHash string `json:"element_hash"`
+	// Error message
+	Message string `json:"message"`
+}
+
+func (PassportElementErrorDataField) passportErrorSource() string        { return "data" }
+func (PassportElementErrorFrontSide) passportErrorSource() string        { return "front_side" }
+func (PassportElementErrorReverseSide) passportErrorSource() string      { return "reverse_side" }
+func (PassportElementErrorSelfie) passportErrorSource() string           { return "selfie" }
+func (PassportElementErrorFile) passportErrorSource() string             { return "file" }
+func (PassportElementErrorFiles) passportErrorSource() string            { return "files" }
+func (PassportElementErrorTranslationFile) passportErrorSource() string  { return "translation_file" }
+func (PassportElementErrorTranslationFiles) passportErrorSource() string { return "translation_files" }
+func (PassportElementErrorUnspecified) passportErrorSource() string      { return "unspecified" }
+
+// marshalPassportErrors serializes the errors with their source fields.
+func marshalPassportErrors(errs []PassportElementError) (string, error) {
+	fields := make([]map[string]interface{}, 0, len(errs))
+
+	for _, e := range errs {
+		b, err := json.Marshal(e)
+
+		if err != nil {
+			return "", err
+		}
+
+		var field map[string]interface{}
+
+		if err = json.Unmarshal(b, &field); err != nil {
+			return "", err
+		}
+
+		field["source"] = e.passportErrorSource()
+		fields = append(fields, field)
+	}
+
+	b, err := json.Marshal(fields)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,196 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+var b64 = base64.StdEncoding.EncodeToString
+
+// passportEncrypt encrypts the data the way Telegram does, with a random padding of padding bytes
+// whose first byte is the length of the padding.
+func passportEncrypt(t *testing.T, plain []byte, padding int) (encrypted, secret, hash []byte) {
+	t.Helper()
+	data := make([]byte, padding+len(plain))
+	rand.Read(data[:padding])
+	data[0] = byte(padding)
+	copy(data[padding:], plain)
+
+	sum := sha256.Sum256(data)
+	hash = sum[:]
+	secret = make([]byte, 32)
+	rand.Read(secret)
+	secretHash := sha512.Sum512(append(append([]byte{}, secret...), hash...))
+	block, err := aes.NewCipher(secretHash[:32])
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted = make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, secretHash[32:48]).CryptBlocks(encrypted, data)
+
+	return encrypted, secret, hash
+}
+
+// passportPadding returns a padding of 32 to 255 bytes that makes the data a multiple of the AES block size.
+func passportPadding(plain []byte) int {
+	return 32 + (aes.BlockSize-len(plain)%aes.BlockSize)%aes.BlockSize
+}
+
+func newPassportKey(t *testing.T) (*rsa.PrivateKey, *tgbot.PassportDecryptor) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := tgbot.NewPassportDecryptor(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, d
+}
+
+// passportData returns the passport data of an element with the personal details, and the credentials
+// for it only if withCredentials.
+func passportData(t *testing.T, key *rsa.PrivateKey, details tgbot.PersonalDetails,
+	withCredentials bool) *tgbot.PassportData {
+	t.Helper()
+	detailsJson, _ := json.Marshal(details)
+	data, dataSecret, dataHash := passportEncrypt(t, detailsJson, passportPadding(detailsJson))
+
+	var creds tgbot.Credentials
+	creds.Nonce = "nonce"
+
+	if withCredentials {
+		creds.SecureData.PersonalDetails = &tgbot.SecureValue{
+			Data: &tgbot.DataCredentials{DataHash: b64(dataHash), Secret: b64(dataSecret)},
+		}
+	}
+
+	credsJson, _ := json.Marshal(creds)
+	credsData, credsSecret, credsHash := passportEncrypt(t, credsJson, passportPadding(credsJson))
+	encryptedSecret, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, &key.PublicKey, credsSecret, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tgbot.PassportData{
+		Data: []tgbot.EncryptedPassportElement{
+			{Type: "personal_details", Data: b64(data), Hash: "element hash"},
+			{Type: "email", Email: "jane@example.com", Hash: "email hash"},
+		},
+		Credentials: &tgbot.EncryptedCredentials{Data: b64(credsData), Hash: b64(credsHash), Secret: b64(encryptedSecret)},
+	}
+}
+
+func TestPassportDecryptorDecrypt(t *testing.T) {
+	key, d := newPassportKey(t)
+	details := tgbot.PersonalDetails{FirstName: "Jane", LastName: "Doe", BirthDate: "01.02.1990", Gender: "female"}
+	creds, elements, err := d.Decrypt(nil, passportData(t, key, details, true))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if creds.Nonce != "nonce" {
+		t.Errorf("nonce = %q", creds.Nonce)
+	}
+
+	if len(elements) != 2 || elements[1].Email != "jane@example.com" {
+		t.Fatalf("elements = %+v", elements)
+	}
+
+	var got tgbot.PersonalDetails
+
+	if err = json.Unmarshal(elements[0].Data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got != details || elements[0].Hash != "element hash" {
+		t.Errorf("personal details = %+v, hash %q", got, elements[0].Hash)
+	}
+}
+
+func TestPassportDecryptorRejectsElementsWithoutCredentials(t *testing.T) {
+	key, d := newPassportKey(t)
+	_, _, err := d.Decrypt(nil, passportData(t, key, tgbot.PersonalDetails{FirstName: "Jane"}, false))
+
+	if err == nil || !strings.Contains(err.Error(), "no credentials for passport element personal_details") {
+		t.Errorf("Decrypt = %v, want an error for the element without credentials", err)
+	}
+}
+
+func TestPassportDecryptorRejectsOtherKeys(t *testing.T) {
+	key, _ := newPassportKey(t)
+	_, other := newPassportKey(t)
+
+	if _, _, err := other.Decrypt(nil, passportData(t, key, tgbot.PersonalDetails{}, true)); err == nil {
+		t.Error("credentials encrypted for another key were decrypted")
+	}
+}
+
+func TestPassportDecryptorDecryptFile(t *testing.T) {
+	_, d := newPassportKey(t)
+	plain := []byte("scan of the passport")
+	encrypted, secret, hash := passportEncrypt(t, plain, passportPadding(plain))
+	fc := &tgbot.FileCredentials{FileHash: b64(hash), Secret: b64(secret)}
+
+	got, err := d.DecryptFile(fc, encrypted)
+
+	if err != nil || string(got) != string(plain) {
+		t.Fatalf("DecryptFile = %q, %v", got, err)
+	}
+
+	tampered := append([]byte{}, encrypted...)
+	tampered[len(tampered)-1] ^= 1
+
+	if _, err = d.DecryptFile(fc, tampered); err == nil || !strings.Contains(err.Error(), "hash mismatch") {
+		t.Errorf("DecryptFile of tampered data = %v, want a hash mismatch", err)
+	}
+
+	otherHash := sha256.Sum256([]byte("other"))
+
+	if _, err = d.DecryptFile(&tgbot.FileCredentials{FileHash: b64(otherHash[:]), Secret: fc.Secret}, encrypted); err == nil {
+		t.Error("DecryptFile with another hash succeeded")
+	}
+
+	if _, err = d.DecryptFile(fc, encrypted[:len(encrypted)-1]); err == nil || !strings.Contains(err.Error(), "length") {
+		t.Errorf("DecryptFile of truncated data = %v, want a length error", err)
+	}
+
+	// Telegram pads with 32 to 255 bytes: a shorter padding is rejected even with a valid hash.
+	short := []byte("0123456789abcdef")
+	encrypted, secret, hash = passportEncrypt(t, short, 16)
+	fc = &tgbot.FileCredentials{FileHash: b64(hash), Secret: b64(secret)}
+
+	if _, err = d.DecryptFile(fc, encrypted); err == nil || !strings.Contains(err.Error(), "padding") {
+		t.Errorf("DecryptFile with a 16 byte padding = %v, want a padding error", err)
+	}
+}
@@ -0,0 +1,175 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// PreCheckoutTimeout is the time Telegram waits for the answer to a pre-checkout query
+	// before the checkout is cancelled.
+	PreCheckoutTimeout = 10 * time.Second
+	// DefaultPreCheckoutDeadline is the time a PaymentHandler gives the PreCheckoutQuery callback,
+	// leaving the rest of PreCheckoutTimeout for the answer to reach Telegram.
+	DefaultPreCheckoutDeadline = 8 * time.Second
+	// DefaultPaymentErrorMessage is shown to the user when a query is declined for an internal reason.
+	DefaultPaymentErrorMessage = "The payment could not be processed. Please try again later."
+)
+
+// PaymentError is returned by payment callbacks to decline a query. Message is shown to the user.
+type PaymentError struct {
+	Message string
+}
+
+func (e *PaymentError) Error() string {
+	return e.Message
+}
+
+// PaymentHandler answers shipping and pre-checkout queries. Every query passed to it is answered
+// exactly once: pre-checkout queries whose callback does not return within Deadline, panics or is
+// not set are declined, so Telegram never waits for an answer that does not come.
+type PaymentHandler struct {
+	Bot *Bot
+	// ShippingQuery returns the shipping options available for the address in the query.
+	// Returning an error declines the query.
+	ShippingQuery func(q *ShippingQuery) ([]ShippingOption, error)
+	// PreCheckoutQuery confirms that the order can be fulfilled. Returning an error declines the checkout.
+	PreCheckoutQuery func(q *PreCheckoutQuery) error
+	// Deadline is the time the PreCheckoutQuery callback is given, DefaultPreCheckoutDeadline if zero.
+	Deadline time.Duration
+	// ErrorMessage is shown to the user when a query is declined by an error other than PaymentError,
+	// DefaultPaymentErrorMessage if empty.
+	ErrorMessage string
+}
+
+// HandleShippingQuery calls the ShippingQuery callback and answers the query with its result.
+// The error of the callback, unless it is or wraps a PaymentError, is returned after the query is declined.
+func (h *PaymentHandler) HandleShippingQuery(q *ShippingQuery) error {
+	if h.ShippingQuery == nil {
+		_, err := h.Bot.AnswerShippingQuery(q.Id, false, nil, h.errorMessage())
+
+		return err
+	}
+
+	options, cbErr := h.callShipping(q)
+
+	if cbErr != nil {
+		if _, err := h.Bot.AnswerShippingQuery(q.Id, false, nil, h.declineMessage(cbErr)); err != nil {
+			return err
+		}
+
+		var pe *PaymentError
+
+		if errors.As(cbErr, &pe) {
+			return nil
+		}
+
+		return cbErr
+	}
+
+	_, err := h.Bot.AnswerShippingQuery(q.Id, true, options, "")
+
+	return err
+}
+
+// HandlePreCheckoutQuery calls the PreCheckoutQuery callback and answers the query with its result.
+// If the callback does not return within the deadline, the query is declined and the callback's result
+// is discarded. The answer is abandoned if it cannot be sent within PreCheckoutTimeout of the call,
+// when Telegram no longer waits for it.
+func (h *PaymentHandler) HandlePreCheckoutQuery(q *PreCheckoutQuery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), PreCheckoutTimeout)
+	defer cancel()
+
+	if h.PreCheckoutQuery == nil {
+		_, err := h.Bot.answerPreCheckoutQuery(ctx, q.Id, false, h.errorMessage())
+
+		return err
+	}
+
+	deadline := h.Deadline
+
+	if deadline <= 0 {
+		deadline = DefaultPreCheckoutDeadline
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- h.callPreCheckout(q)
+	}()
+
+	timer := time.NewTimer(deadline)
+	defer timer.Stop()
+
+	select {
+	case cbErr := <-done:
+		if cbErr == nil {
+			_, err := h.Bot.answerPreCheckoutQuery(ctx, q.Id, true, "")
+
+			return err
+		}
+
+		if _, err := h.Bot.answerPreCheckoutQuery(ctx, q.Id, false, h.declineMessage(cbErr)); err != nil {
+			return err
+		}
+
+		var pe *PaymentError
+
+		if errors.As(cbErr, &pe) {
+			return nil
+		}
+
+		return cbErr
+	case <-timer.C:
+		if _, err := h.Bot.answerPreCheckoutQuery(ctx, q.Id, false, h.errorMessage()); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("tgbot: pre-checkout query %s was not confirmed within %s", q.Id, deadline)
+	}
+}
+
+func (h *PaymentHandler) callShipping(q *ShippingQuery) (options []ShippingOption, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("tgbot: shipping query callback panicked: %v", r)
+		}
+	}()
+
+	return h.ShippingQuery(q)
+}
+
+func (h *PaymentHandler) callPreCheckout(q *PreCheckoutQuery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("tgbot: pre-checkout query callback panicked: %v", r)
+		}
+	}()
+
+	return h.PreCheckoutQuery(q)
+}
+
+func (h *PaymentHandler) declineMessage(err error) string {
+	var pe *PaymentError
+
+	if errors.As(err, &pe) && pe.Message != "" {
+		return pe.Message
+	}
+
+	return h.errorMessage()
+}
+
+func (h *PaymentHandler) errorMessage() string {
+	if h.ErrorMessage != "" {
+		return h.ErrorMessage
+	}
+
+	return DefaultPaymentErrorMessage
+}
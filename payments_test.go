@@ -0,0 +1,119 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+func lastAnswer(t *testing.T, api *stubApi, method string) map[string]string {
+	t.Helper()
+	calls := api.Calls(method)
+
+	if len(calls) != 1 {
+		t.Fatalf("%d %s requests, want 1", len(calls), method)
+	}
+
+	return calls[0].params
+}
+
+func TestPaymentHandlerConfirmsPreCheckoutQuery(t *testing.T) {
+	api := newStubApi(t, nil)
+	h := &tgbot.PaymentHandler{
+		Bot:              &tgbot.Bot{Token: "123:abc"},
+		PreCheckoutQuery: func(q *tgbot.PreCheckoutQuery) error { return nil },
+	}
+	if err := h.HandlePreCheckoutQuery(&tgbot.PreCheckoutQuery{Id: "q"}); err != nil {
+		t.Fatal(err)
+	}
+
+	end := time.Now()
+
+	call := api.Calls("answerPreCheckoutQuery")[0]
+
+	if call.params["pre_checkout_query_id"] != "q" || call.params["ok"] != "true" {
+		t.Errorf("answer = %v", call.params)
+	}
+
+	// The answer is abandoned when Telegram no longer waits for it.
+	if call.deadline.IsZero() || call.deadline.After(end.Add(tgbot.PreCheckoutTimeout)) {
+		t.Errorf("answer deadline %v, want at most %v after the query", call.deadline, tgbot.PreCheckoutTimeout)
+	}
+}
+
+func TestPaymentHandlerDeclinesLateConfirmations(t *testing.T) {
+	api := newStubApi(t, nil)
+	release := make(chan struct{})
+	defer close(release)
+
+	h := &tgbot.PaymentHandler{
+		Bot:      &tgbot.Bot{Token: "123:abc"},
+		Deadline: 20 * time.Millisecond,
+		PreCheckoutQuery: func(q *tgbot.PreCheckoutQuery) error {
+			<-release
+
+			return nil
+		},
+	}
+
+	err := h.HandlePreCheckoutQuery(&tgbot.PreCheckoutQuery{Id: "q"})
+
+	if err == nil || !strings.Contains(err.Error(), "not confirmed") {
+		t.Errorf("HandlePreCheckoutQuery = %v, want a deadline error", err)
+	}
+
+	if answer := lastAnswer(t, api, "answerPreCheckoutQuery"); answer["ok"] != "false" ||
+		answer["error_message"] != tgbot.DefaultPaymentErrorMessage {
+		t.Errorf("answer = %v", answer)
+	}
+}
+
+func TestPaymentHandlerDeclinesPanics(t *testing.T) {
+	api := newStubApi(t, nil)
+	h := &tgbot.PaymentHandler{
+		Bot:              &tgbot.Bot{Token: "123:abc"},
+		ErrorMessage:     "Sorry",
+		PreCheckoutQuery: func(q *tgbot.PreCheckoutQuery) error { panic("boom") },
+		ShippingQuery:    func(q *tgbot.ShippingQuery) ([]tgbot.ShippingOption, error) { panic("boom") },
+	}
+
+	if err := h.HandlePreCheckoutQuery(&tgbot.PreCheckoutQuery{Id: "q"}); err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Errorf("HandlePreCheckoutQuery = %v, want a panic error", err)
+	}
+
+	if err := h.HandleShippingQuery(&tgbot.ShippingQuery{Id: "s"}); err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Errorf("HandleShippingQuery = %v, want a panic error", err)
+	}
+
+	for _, method := range []string{"answerPreCheckoutQuery", "answerShippingQuery"} {
+		if answer := lastAnswer(t, api, method); answer["ok"] != "false" || answer["error_message"] != "Sorry" {
+			t.Errorf("%s = %v", method, answer)
+		}
+	}
+}
+
+func TestPaymentHandlerShowsPaymentErrors(t *testing.T) {
+	api := newStubApi(t, nil)
+	h := &tgbot.PaymentHandler{
+		Bot: &tgbot.Bot{Token: "123:abc"},
+		PreCheckoutQuery: func(q *tgbot.PreCheckoutQuery) error {
+			return fmt.Errorf("checking stock: %w", &tgbot.PaymentError{Message: "Out of stock"})
+		},
+	}
+
+	if err := h.HandlePreCheckoutQuery(&tgbot.PreCheckoutQuery{Id: "q"}); err != nil {
+		t.Errorf("HandlePreCheckoutQuery = %v, want nil for a PaymentError", err)
+	}
+
+	if answer := lastAnswer(t, api, "answerPreCheckoutQuery"); answer["ok"] != "false" || answer["error_message"] != "Out of stock" {
+		t.Errorf("answer = %v", answer)
+	}
+}
@@ -0,0 +1,344 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPollTimeout     = 30
+	DefaultRetryDelay      = 3 * time.Second
+	DefaultShutdownTimeout = 10 * time.Second
+)
+
+// OffsetStore persists the identifier of the next update a poller should receive.
+type OffsetStore interface {
+	// LoadOffset returns the saved offset, or zero if there is none.
+	LoadOffset() (int, error)
+	// SaveOffset saves the offset.
+	SaveOffset(offset int) error
+}
+
+type storageOffsetStore struct {
+	storage Storage
+	key     string
+}
+
+// NewStorageOffsetStore returns an OffsetStore that keeps the offset in the storage under the key.
+func NewStorageOffsetStore(storage Storage, key string) OffsetStore {
+	return &storageOffsetStore{storage: storage, key: key}
+}
+
+func (s *storageOffsetStore) LoadOffset() (int, error) {
+	data, ok, err := s.storage.Get(s.key)
+
+	if err != nil || !ok {
+		return 0, err
+	}
+
+	return strconv.Atoi(string(data))
+}
+
+func (s *storageOffsetStore) SaveOffset(offset int) error {
+	return s.storage.Set(s.key, []byte(strconv.Itoa(offset)), 0)
+}
+
+// Poller receives updates with long polling and passes them to a dispatcher.
+type Poller struct {
+	Bot *Bot
+	// Timeout of long polling in seconds, DefaultPollTimeout if zero
+	Timeout int
+	// Limit of the number of updates to be retrieved at once, 1-100
+	Limit int
+	// AllowedUpdates lists the kinds of updates the bot receives, all kinds except poll answers if nil
+	AllowedUpdates []string
+	// Offset is the identifier of the next update to be received
+	Offset int
+	// RetryDelay is the time to wait after a failed request, DefaultRetryDelay if zero
+	RetryDelay time.Duration
+	// OnError is called with the errors of getUpdates requests and of the offset store.
+	// If nil, the errors are logged with the standard logger.
+	OnError func(err error)
+	// OffsetStore persists the offset of the last processed update, so that polling resumes
+	// where it left off after a restart or a crash. Optional.
+	OffsetStore OffsetStore
+	// ShutdownTimeout is the time Run waits for the dispatched updates to be processed after
+	// ctx is done, DefaultShutdownTimeout if zero.
+	ShutdownTimeout time.Duration
+
+	mu       sync.Mutex
+	inflight map[int]bool
+	last     int
+	saveMu   sync.Mutex
+	saved    int
+}
+
+// Run polls updates and dispatches them until ctx is done. Dispatch blocks while the dispatcher's queue
+// is full, so no more updates are requested than the dispatcher can take.
+//
+// When ctx is done, Run stops fetching updates, flushes the updates held back by the middlewares
+// of the dispatcher, waits up to ShutdownTimeout for the updates it dispatched to be processed, and then
+// confirms the processed updates to Telegram, so that they are not received again. Updates that were
+// not processed in time are received again the next time polling starts.
+// Run returns nil if all dispatched updates were processed and confirmed.
+func (p *Poller) Run(ctx context.Context, d *Dispatcher) error {
+	timeout := p.Timeout
+
+	if timeout <= 0 {
+		timeout = DefaultPollTimeout
+	}
+
+	if p.OffsetStore != nil {
+		offset, err := p.OffsetStore.LoadOffset()
+
+		if err != nil {
+			return err
+		}
+
+		if offset > p.Offset {
+			p.Offset = offset
+		}
+	}
+
+	p.mu.Lock()
+	p.inflight = make(map[int]bool)
+	p.last = p.Offset - 1
+	p.mu.Unlock()
+
+	err := p.poll(ctx, d, timeout)
+
+	if ctx.Err() == nil {
+		return err
+	}
+
+	return p.shutdown(d)
+}
+
+func (p *Poller) poll(ctx context.Context, d *Dispatcher, timeout int) error {
+	for {
+		updates, err := p.Bot.getUpdates(ctx, &GetUpdatesOptions{
+			Offset:         p.Offset,
+			Limit:          p.Limit,
+			Timeout:        timeout,
+			AllowedUpdates: p.AllowedUpdates,
+		})
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			p.reportError(err)
+
+			if err = p.wait(ctx); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		for i := range updates {
+			id := updates[i].UpdateId
+
+			// The update counts as received before it is dispatched, as it may be processed,
+			// and the offset after it saved, before dispatch returns.
+			p.mu.Lock()
+			p.inflight[id] = true
+			last := p.last
+
+			if id > p.last {
+				p.last = id
+			}
+
+			p.mu.Unlock()
+
+			err = d.dispatch(ctx, p.Bot, &updates[i], func() {
+				p.processed(id)
+			})
+
+			if err != nil {
+				p.mu.Lock()
+				delete(p.inflight, id)
+				p.last = last
+				p.mu.Unlock()
+
+				return err
+			}
+
+			p.Offset = id + 1
+		}
+	}
+}
+
+// processed marks the update as processed and saves the new offset.
+func (p *Poller) processed(id int) {
+	p.mu.Lock()
+	before := p.committed()
+	delete(p.inflight, id)
+	after := p.committed()
+	p.mu.Unlock()
+
+	if p.OffsetStore != nil && after > before {
+		if err := p.saveOffset(after + 1); err != nil {
+			p.reportError(err)
+		}
+	}
+}
+
+// saveOffset saves the offset unless a greater one was saved by another worker.
+func (p *Poller) saveOffset(offset int) error {
+	p.saveMu.Lock()
+	defer p.saveMu.Unlock()
+
+	if offset <= p.saved {
+		return nil
+	}
+
+	if err := p.OffsetStore.SaveOffset(offset); err != nil {
+		return err
+	}
+
+	p.saved = offset
+
+	return nil
+}
+
+// committed returns the identifier of the last update that, together with all updates before it,
+// was processed. p.mu must be held.
+func (p *Poller) committed() int {
+	committed := p.last
+
+	for id := range p.inflight {
+		if id <= committed {
+			committed = id - 1
+		}
+	}
+
+	return committed
+}
+
+// shutdown flushes the dispatcher, waits for the dispatched updates to be processed and confirms them.
+func (p *Poller) shutdown(d *Dispatcher) error {
+	timeout := p.ShutdownTimeout
+
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	d.Flush()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	var drainErr error
+
+	for drainErr == nil {
+		p.mu.Lock()
+		pending := len(p.inflight)
+		p.mu.Unlock()
+
+		if pending == 0 {
+			break
+		}
+
+		select {
+		case <-ticker.C:
+			d.flushIdle()
+		case <-ctx.Done():
+			drainErr = ctx.Err()
+		}
+	}
+
+	p.mu.Lock()
+	offset := p.committed() + 1
+	p.mu.Unlock()
+
+	if offset <= 0 {
+		return drainErr
+	}
+
+	confirmCtx, confirmCancel := context.WithTimeout(context.Background(), timeout)
+	defer confirmCancel()
+
+	// Requesting updates with an offset confirms all updates before it.
+	_, err := p.Bot.getUpdates(confirmCtx, &GetUpdatesOptions{Offset: offset, Limit: 1, AllowedUpdates: p.AllowedUpdates})
+
+	if err != nil {
+		return err
+	}
+
+	if p.OffsetStore != nil {
+		if err = p.saveOffset(offset); err != nil {
+			return err
+		}
+	}
+
+	return drainErr
+}
+
+func (p *Poller) wait(ctx context.Context) error {
+	delay := p.RetryDelay
+
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Poller) reportError(err error) {
+	if p.OnError != nil {
+		p.OnError(err)
+	} else {
+		log.Printf("%v", err)
+	}
+}
+
+// WebhookHandler returns an HTTP handler that passes the updates Telegram posts to the webhook
+// of the bot to the dispatcher. The request is not answered until the update is queued, so Telegram
+// delivers updates no faster than the dispatcher takes them.
+func (d *Dispatcher) WebhookHandler(bot *Bot) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		var u Update
+
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := d.Dispatch(r.Context(), bot, &u); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
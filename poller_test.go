@@ -0,0 +1,247 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+// updateQueue answers getUpdates requests with the updates it holds from the requested offset,
+// holding long polling requests that find none for a short while.
+type updateQueue struct {
+	mu      sync.Mutex
+	updates []tgbot.Update
+	offsets []int
+}
+
+func (q *updateQueue) add(updateId int, chatId int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.updates = append(q.updates, tgbot.Update{
+		UpdateId: updateId,
+		Message:  &tgbot.Message{MessageId: updateId, Chat: &tgbot.Chat{Id: chatId, Type: "private"}},
+	})
+}
+
+func (q *updateQueue) handle(ctx context.Context, method string, params map[string]string) (interface{}, error) {
+	if method != "getUpdates" {
+		return true, nil
+	}
+
+	offset, _ := strconv.Atoi(params["offset"])
+	updates := []tgbot.Update{}
+
+	q.mu.Lock()
+	q.offsets = append(q.offsets, offset)
+
+	for _, u := range q.updates {
+		if u.UpdateId >= offset {
+			updates = append(updates, u)
+		}
+	}
+
+	q.mu.Unlock()
+
+	if len(updates) > 0 || params["timeout"] == "" {
+		return updates, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(20 * time.Millisecond):
+		return updates, nil
+	}
+}
+
+// lastOffset returns the offset of the last getUpdates request.
+func (q *updateQueue) lastOffset() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.offsets) == 0 {
+		return -1
+	}
+
+	return q.offsets[len(q.offsets)-1]
+}
+
+// pollerTest runs a poller whose handler blocks on the updates of blocked chats until they are released.
+type pollerTest struct {
+	queue   *updateQueue
+	store   tgbot.OffsetStore
+	poller  *tgbot.Poller
+	d       *tgbot.Dispatcher
+	cancel  context.CancelFunc
+	done    chan error
+	mu      sync.Mutex
+	handled []int
+	blocked map[int64]chan struct{}
+}
+
+func newPollerTest(t *testing.T, store tgbot.OffsetStore, blockedChats ...int64) *pollerTest {
+	pt := &pollerTest{queue: &updateQueue{}, store: store, blocked: make(map[int64]chan struct{})}
+	newStubApi(t, pt.queue.handle)
+
+	for _, id := range blockedChats {
+		pt.blocked[id] = make(chan struct{})
+	}
+
+	pt.d = tgbot.NewDispatcher(tgbot.HandlerFunc(func(c *tgbot.Context) error {
+		if ch, ok := pt.blocked[c.Update.Message.Chat.Id]; ok {
+			<-ch
+		}
+
+		pt.mu.Lock()
+		pt.handled = append(pt.handled, c.Update.UpdateId)
+		pt.mu.Unlock()
+
+		return nil
+	}), 4, 16)
+	t.Cleanup(func() { pt.d.Shutdown(context.Background()) })
+
+	pt.poller = &tgbot.Poller{Bot: &tgbot.Bot{Token: "123:abc"}, Timeout: 1, OffsetStore: store}
+
+	return pt
+}
+
+func (pt *pollerTest) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	pt.cancel = cancel
+	pt.done = make(chan error, 1)
+
+	go func() {
+		pt.done <- pt.poller.Run(ctx, pt.d)
+	}()
+}
+
+func (pt *pollerTest) handledIds() []int {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	return append([]int(nil), pt.handled...)
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func savedOffset(t *testing.T, store tgbot.OffsetStore) int {
+	t.Helper()
+	offset, err := store.LoadOffset()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return offset
+}
+
+func TestPollerSavesOffsetWhileRunning(t *testing.T) {
+	store := tgbot.NewStorageOffsetStore(tgbot.NewMemoryStorage(), "offset")
+	pt := newPollerTest(t, store)
+	pt.queue.add(1, 10)
+	pt.queue.add(2, 20)
+	pt.run()
+
+	// The offset is saved as soon as the updates are processed, not only on shutdown.
+	eventually(t, "the offset after update 2", func() bool { return savedOffset(t, store) == 3 })
+	pt.cancel()
+
+	if err := <-pt.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPollerSavesOffsetOfUpdatesProcessedInOrder(t *testing.T) {
+	store := tgbot.NewStorageOffsetStore(tgbot.NewMemoryStorage(), "offset")
+	pt := newPollerTest(t, store, 10)
+	pt.queue.add(1, 10)
+	pt.queue.add(2, 20)
+	pt.queue.add(3, 20)
+	pt.run()
+
+	// Updates 2 and 3 are processed while update 1 of another chat is not.
+	eventually(t, "updates 2 and 3", func() bool { return len(pt.handledIds()) == 2 })
+
+	if offset := savedOffset(t, store); offset != 0 {
+		t.Errorf("offset saved before update 1 was processed: %d", offset)
+	}
+
+	close(pt.blocked[10])
+	eventually(t, "the offset after update 3", func() bool { return savedOffset(t, store) == 4 })
+	pt.cancel()
+
+	if err := <-pt.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPollerResumesFromSavedOffset(t *testing.T) {
+	store := tgbot.NewStorageOffsetStore(tgbot.NewMemoryStorage(), "offset")
+
+	if err := store.SaveOffset(3); err != nil {
+		t.Fatal(err)
+	}
+
+	pt := newPollerTest(t, store)
+
+	for id := 1; id <= 4; id++ {
+		pt.queue.add(id, 10)
+	}
+
+	pt.run()
+	eventually(t, "the updates", func() bool { return len(pt.handledIds()) == 2 })
+	pt.cancel()
+
+	if err := <-pt.done; err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := pt.handledIds(), []int{3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("handled updates %v, want %v", got, want)
+	}
+}
+
+func TestPollerConfirmsOnlyProcessedUpdatesOnShutdown(t *testing.T) {
+	store := tgbot.NewStorageOffsetStore(tgbot.NewMemoryStorage(), "offset")
+	pt := newPollerTest(t, store, 20)
+	defer close(pt.blocked[20])
+	pt.poller.ShutdownTimeout = 50 * time.Millisecond
+	pt.queue.add(1, 10)
+	pt.queue.add(2, 20)
+	pt.queue.add(3, 10)
+	pt.run()
+
+	eventually(t, "updates 1 and 3", func() bool { return len(pt.handledIds()) == 2 })
+	pt.cancel()
+
+	if err := <-pt.done; err != context.DeadlineExceeded {
+		t.Errorf("Run = %v, want context.DeadlineExceeded", err)
+	}
+
+	if offset := pt.queue.lastOffset(); offset != 2 {
+		t.Errorf("confirmed updates before %d, want 2", offset)
+	}
+
+	if offset := savedOffset(t, store); offset != 2 {
+		t.Errorf("saved offset %d, want 2", offset)
+	}
+}
@@ -0,0 +1,225 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter delays the requests of bots to stay within the limits of the Bot API. A single
+// rate limiter can be shared by several bots.
+type RateLimiter interface {
+	// Wait blocks until the bot may send a request for the method with the chat_id parameter chatId,
+	// which is empty for methods without a chat, or until ctx is done. messages is the number of messages
+	// the request sends, e.g. the number of media of sendMediaGroup, and 1 for other methods.
+	Wait(ctx context.Context, bot *Bot, method, chatId string, messages int) error
+}
+
+// Rate is a number of requests allowed per period. The requests can be sent at once, after which
+// they are spread evenly over the period. The zero Rate imposes no limit.
+type Rate struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Limiter is a RateLimiter for the methods that send messages, e.g. sendMessage, sendPhoto,
+// forwardMessage and copyMessage. Other requests are not delayed.
+type Limiter struct {
+	// Bot limits the messages sent by each bot.
+	Bot Rate
+	// Chat limits the messages sent by each bot to each private chat.
+	Chat Rate
+	// Group limits the messages sent by each bot to each group, supergroup or channel.
+	Group Rate
+
+	mu        sync.Mutex
+	buckets   map[limitKey]*rateBucket
+	lastSweep time.Time
+}
+
+type limitKey struct {
+	bot  string
+	chat string
+}
+
+// limit is a rate applying to the requests counted in a bucket.
+type limit struct {
+	key  limitKey
+	rate Rate
+}
+
+// rateBucket schedules requests with the generic cell rate algorithm.
+type rateBucket struct {
+	// tat is the theoretical arrival time of the next request if requests were sent evenly.
+	tat time.Time
+}
+
+// NewLimiter returns a limiter with the limits Telegram documents for sending messages: 30 messages
+// per second per bot, one message per second per chat and 20 messages per minute per group.
+func NewLimiter() *Limiter {
+	return &Limiter{
+		Bot:   Rate{Requests: 30, Per: time.Second},
+		Chat:  Rate{Requests: 1, Per: time.Second},
+		Group: Rate{Requests: 20, Per: time.Minute},
+	}
+}
+
+// Wait implements RateLimiter. A request is sent once both the limit of the bot and the limit of its chat
+// allow it, and counts against both at that time. A request sending more messages than a limit allows
+// at once is not delayed by that limit, but the following requests wait as if the messages were sent
+// one by one. A request whose wait is cancelled still counts against the limits.
+func (l *Limiter) Wait(ctx context.Context, bot *Bot, method, chatId string, messages int) error {
+	if !sendsMessage(method) {
+		return nil
+	}
+
+	now := time.Now()
+	delay := l.reserve(bot, chatId, messages, now).Sub(now)
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve returns the time the bot may send the messages to the chat at, and counts them against
+// the limits at that time.
+func (l *Limiter) reserve(bot *Bot, chatId string, messages int, now time.Time) time.Time {
+	if messages < 1 {
+		messages = 1
+	}
+
+	id := botId(bot)
+	limits := []limit{{limitKey{bot: id}, l.Bot}}
+
+	if chatId != "" {
+		rate := l.Chat
+
+		if strings.HasPrefix(chatId, "-") || strings.HasPrefix(chatId, "@") {
+			rate = l.Group
+		}
+
+		limits = append(limits, limit{limitKey{bot: id, chat: chatId}, rate})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	at := now
+
+	for _, lim := range limits {
+		if t := l.bucket(lim.key).allowed(lim.rate, messages); t.After(at) {
+			at = t
+		}
+	}
+
+	for _, lim := range limits {
+		l.bucket(lim.key).take(lim.rate, messages, at)
+	}
+
+	return at
+}
+
+// bucket returns the bucket of the key, creating it if needed. l.mu must be held.
+func (l *Limiter) bucket(key limitKey) *rateBucket {
+	if l.buckets == nil {
+		l.buckets = make(map[limitKey]*rateBucket)
+	}
+
+	b, ok := l.buckets[key]
+
+	if !ok {
+		b = &rateBucket{}
+		l.buckets[key] = b
+	}
+
+	return b
+}
+
+// allowed returns the earliest time the messages may be sent at within the rate. Messages beyond
+// the burst of the rate are allowed as soon as the burst is.
+func (b *rateBucket) allowed(rate Rate, messages int) time.Time {
+	if rate.Requests <= 0 || rate.Per <= 0 {
+		return time.Time{}
+	}
+
+	if messages > rate.Requests {
+		messages = rate.Requests
+	}
+
+	interval := rate.Per / time.Duration(rate.Requests)
+
+	return b.tat.Add(-interval * time.Duration(rate.Requests-messages))
+}
+
+// take counts the messages sent at the time against the rate.
+func (b *rateBucket) take(rate Rate, messages int, at time.Time) {
+	if rate.Requests <= 0 || rate.Per <= 0 {
+		return
+	}
+
+	if b.tat.Before(at) {
+		b.tat = at
+	}
+
+	b.tat = b.tat.Add(rate.Per / time.Duration(rate.Requests) * time.Duration(messages))
+}
+
+// sweep forgets the buckets that no longer delay requests, at most once a minute. l.mu must be held.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tat.Before(now) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func sendsMessage(method string) bool {
+	switch method {
+	case "sendChatAction":
+		return false
+	case "forwardMessage", "copyMessage":
+		return true
+	}
+
+	return strings.HasPrefix(method, "send")
+}
+
+// messageCount returns the number of messages sent by a request for the method with the params:
+// the number of media of sendMediaGroup, and 1 otherwise.
+func messageCount(method string, params map[string]string) int {
+	if method != "sendMediaGroup" {
+		return 1
+	}
+
+	var media []json.RawMessage
+
+	if err := json.Unmarshal([]byte(params["media"]), &media); err != nil || len(media) == 0 {
+		return 1
+	}
+
+	return len(media)
+}
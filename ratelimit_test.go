@@ -0,0 +1,139 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+	"github.com/modern-dev/tgbot-go/tgbottest"
+)
+
+var t0 = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type reservation struct {
+	chatId   string
+	messages int
+	want     time.Duration
+}
+
+func checkReservations(t *testing.T, l *tgbot.Limiter, rs []reservation) {
+	t.Helper()
+	bot := &tgbot.Bot{Token: tgbottest.Token}
+
+	for i, r := range rs {
+		if got := l.Reserve(bot, r.chatId, r.messages, t0).Sub(t0); got != r.want {
+			t.Errorf("request %d to %q sent after %v, want %v", i, r.chatId, got, r.want)
+		}
+	}
+}
+
+func TestLimiterSpacesMessagesToAChat(t *testing.T) {
+	checkReservations(t, &tgbot.Limiter{Chat: tgbot.Rate{Requests: 1, Per: time.Second}}, []reservation{
+		{"7", 1, 0},
+		{"7", 1, time.Second},
+		{"8", 1, 0},
+		{"7", 1, 2 * time.Second},
+	})
+}
+
+func TestLimiterAllowsBurstsOfTheBot(t *testing.T) {
+	checkReservations(t, &tgbot.Limiter{Bot: tgbot.Rate{Requests: 3, Per: 3 * time.Second}}, []reservation{
+		{"", 1, 0},
+		{"1", 1, 0},
+		{"2", 1, 0},
+		{"3", 1, time.Second},
+		{"", 1, 2 * time.Second},
+	})
+}
+
+func TestLimiterUsesTheGroupRateForGroups(t *testing.T) {
+	checkReservations(t, &tgbot.Limiter{
+		Chat:  tgbot.Rate{Requests: 1, Per: time.Second},
+		Group: tgbot.Rate{Requests: 2, Per: time.Minute},
+	}, []reservation{
+		{"-100", 1, 0},
+		{"-100", 1, 0},
+		{"-100", 1, 30 * time.Second},
+		{"@channel", 1, 0},
+	})
+}
+
+func TestLimiterCountsDelayedRequestsWhenSent(t *testing.T) {
+	l := &tgbot.Limiter{Bot: tgbot.Rate{Requests: 1, Per: time.Second}, Chat: tgbot.Rate{Requests: 1, Per: 3 * time.Second}}
+	bot := &tgbot.Bot{Token: tgbottest.Token}
+	var sent []time.Time
+
+	// The second message to chat 7 waits for the limit of the chat, not of the bot.
+	for _, chatId := range []string{"7", "7", "8", "9", "10"} {
+		sent = append(sent, l.Reserve(bot, chatId, 1, t0))
+	}
+
+	sort.Slice(sent, func(i, j int) bool { return sent[i].Before(sent[j]) })
+
+	for i := 1; i < len(sent); i++ {
+		if d := sent[i].Sub(sent[i-1]); d < time.Second {
+			t.Errorf("messages sent %v apart, the bot allows one per second", d)
+		}
+	}
+}
+
+func TestLimiterWeighsMediaGroups(t *testing.T) {
+	// An album larger than the burst is sent at once, and the next messages wait for all its media.
+	checkReservations(t, &tgbot.Limiter{Chat: tgbot.Rate{Requests: 1, Per: time.Second}}, []reservation{
+		{"7", 10, 0},
+		{"7", 1, 10 * time.Second},
+		{"8", 1, 0},
+	})
+	checkReservations(t, &tgbot.Limiter{Bot: tgbot.Rate{Requests: 3, Per: 3 * time.Second}}, []reservation{
+		{"7", 2, 0},
+		{"8", 1, 0},
+		{"9", 5, 3 * time.Second},
+		{"10", 1, 6 * time.Second},
+	})
+}
+
+func TestMessageCount(t *testing.T) {
+	media := `[{"type":"photo","media":"a"},{"type":"photo","media":"b"},{"type":"video","media":"c"}]`
+
+	for _, tc := range []struct {
+		method string
+		params map[string]string
+		want   int
+	}{
+		{"sendMessage", map[string]string{"chat_id": "7"}, 1},
+		{"sendMediaGroup", map[string]string{"media": media}, 3},
+		{"sendMediaGroup", map[string]string{"media": "invalid"}, 1},
+		{"sendMediaGroup", nil, 1},
+	} {
+		if got := tgbot.MessageCount(tc.method, tc.params); got != tc.want {
+			t.Errorf("MessageCount(%q, %v) = %d, want %d", tc.method, tc.params, got, tc.want)
+		}
+	}
+}
+
+func TestLimiterWaitIsCancelled(t *testing.T) {
+	l := &tgbot.Limiter{Chat: tgbot.Rate{Requests: 1, Per: time.Hour}}
+	bot := &tgbot.Bot{Token: tgbottest.Token}
+
+	if err := l.Wait(context.Background(), bot, "sendPhoto", "7", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx, bot, "sendPhoto", "7", 1); err != context.DeadlineExceeded {
+		t.Errorf("Wait = %v, want context.DeadlineExceeded", err)
+	}
+
+	if err := l.Wait(ctx, bot, "getChat", "7", 1); err != nil {
+		t.Errorf("Wait for a method sending no message = %v", err)
+	}
+}
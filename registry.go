@@ -0,0 +1,340 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SecretTokenHeader is the header in which Telegram sends the secret token set with the webhook.
+const SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// BotEntry is a bot in a Registry, with how it receives its updates.
+type BotEntry struct {
+	// Name identifies the bot in the registry.
+	Name string
+	Bot  *Bot
+	// WebhookPath is the URL path of the webhook of the bot served by the registry. Optional.
+	WebhookPath string
+	// WebhookSecret is the secret token set with the webhook of the bot. Requests routed to the bot
+	// by path must carry it; requests with it are routed to the bot whatever their path. Optional.
+	WebhookSecret string
+	// Poller receives the updates of a bot without a webhook while the registry runs. Its Bot is set
+	// by Add. Optional.
+	Poller *Poller
+}
+
+// Registry runs several bots in one process with shared infrastructure: the bots are given the client,
+// the rate limiter, the logger and the instrumentation of the registry unless they have their own,
+// their updates are processed by a single dispatcher, and their polling offsets are kept in a single
+// storage. The registry routes webhook requests to the bots and starts and stops them together:
+//
+//	reg := tgbot.NewRegistry(tgbot.NewDispatcher(router, 0, 0))
+//	reg.RateLimiter = tgbot.NewLimiter()
+//	reg.Add(&tgbot.BotEntry{Name: "support", Bot: support, WebhookPath: "/hook/support", WebhookSecret: secret})
+//	reg.Add(&tgbot.BotEntry{Name: "news", Bot: news, Poller: &tgbot.Poller{}})
+//	reg.Server = &http.Server{Addr: ":8443"}
+//	err := reg.Run(ctx)
+//
+// Handlers tell the bots apart by Context.Bot. Conversations shared by the bots should set SeparateBots.
+type Registry struct {
+	// Dispatcher processes the updates of all bots. Required.
+	Dispatcher *Dispatcher
+	// Client is set as the client of bots without one. Optional.
+	Client *http.Client
+	// RateLimiter is set as the rate limiter of bots without one. Optional.
+	RateLimiter RateLimiter
+	// Logger is set as the logger of bots without one. Optional.
+	Logger Logger
+	// Instrumentation is set as the instrumentation of bots without one. Optional.
+	Instrumentation Instrumentation
+	// Storage keeps the offsets of pollers without an OffsetStore, under keys with the bot identifier.
+	// Optional.
+	Storage Storage
+	// Server serves the webhooks while Run runs, with the registry as handler if its Handler is nil.
+	// Optional.
+	Server *http.Server
+	// ShutdownTimeout is the time Run waits for the updates of the bots to be processed after ctx is done,
+	// DefaultShutdownTimeout if zero.
+	ShutdownTimeout time.Duration
+	// OnShutdown are called in order when Run stops, after the bots stopped receiving updates and before
+	// the dispatcher is shut down, e.g. to flush collectors that hold updates outside the dispatcher.
+	// Their context expires with ShutdownTimeout.
+	OnShutdown []func(ctx context.Context) error
+
+	mu       sync.RWMutex
+	entries  []*BotEntry
+	byName   map[string]*BotEntry
+	byPath   map[string]*BotEntry
+	bySecret map[string]*BotEntry
+}
+
+// NewRegistry returns an empty registry whose bots are processed by the dispatcher.
+func NewRegistry(d *Dispatcher) *Registry {
+	return &Registry{Dispatcher: d}
+}
+
+// Add adds the bot to the registry and gives it the shared infrastructure of the registry. It returns
+// an error if the name, the webhook path or the webhook secret is already used by another bot.
+// Pollers of bots added while Run runs are not started.
+func (r *Registry) Add(e *BotEntry) error {
+	if e.Bot == nil {
+		return fmt.Errorf("tgbot: bot %q has no Bot", e.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.byName == nil {
+		r.byName = make(map[string]*BotEntry)
+		r.byPath = make(map[string]*BotEntry)
+		r.bySecret = make(map[string]*BotEntry)
+	}
+
+	if _, ok := r.byName[e.Name]; ok {
+		return fmt.Errorf("tgbot: bot %q is already registered", e.Name)
+	}
+
+	if _, ok := r.byPath[e.WebhookPath]; ok && e.WebhookPath != "" {
+		return fmt.Errorf("tgbot: webhook path %s is already used", e.WebhookPath)
+	}
+
+	if _, ok := r.bySecret[e.WebhookSecret]; ok && e.WebhookSecret != "" {
+		return fmt.Errorf("tgbot: webhook secret of bot %q is already used", e.Name)
+	}
+
+	bot := e.Bot
+
+	if bot.Client == nil {
+		bot.Client = r.Client
+	}
+
+	if bot.RateLimiter == nil {
+		bot.RateLimiter = r.RateLimiter
+	}
+
+	if bot.Logger == nil {
+		bot.Logger = r.Logger
+	}
+
+	if bot.Instrumentation == nil {
+		bot.Instrumentation = r.Instrumentation
+	}
+
+	if e.Poller != nil && e.Poller.Bot == nil {
+		e.Poller.Bot = bot
+	}
+
+	r.entries = append(r.entries, e)
+	r.byName[e.Name] = e
+
+	if e.WebhookPath != "" {
+		r.byPath[e.WebhookPath] = e
+	}
+
+	if e.WebhookSecret != "" {
+		r.bySecret[e.WebhookSecret] = e
+	}
+
+	return nil
+}
+
+// Remove removes the named bot from the registry, so that its webhook requests are no longer served.
+// Its poller is not stopped.
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.byName[name]
+
+	if !ok {
+		return
+	}
+
+	delete(r.byName, name)
+
+	if r.byPath[e.WebhookPath] == e {
+		delete(r.byPath, e.WebhookPath)
+	}
+
+	if r.bySecret[e.WebhookSecret] == e {
+		delete(r.bySecret, e.WebhookSecret)
+	}
+
+	for i, entry := range r.entries {
+		if entry == e {
+			r.entries = append(r.entries[:i:i], r.entries[i+1:]...)
+
+			break
+		}
+	}
+}
+
+// Get returns the named bot, or nil if there is none.
+func (r *Registry) Get(name string) *Bot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if e, ok := r.byName[name]; ok {
+		return e.Bot
+	}
+
+	return nil
+}
+
+// Entries returns the bots of the registry in the order they were added.
+func (r *Registry) Entries() []*BotEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*BotEntry(nil), r.entries...)
+}
+
+// ServeHTTP passes the update of a webhook request to the dispatcher on behalf of the bot the request
+// is routed to: the bot with the webhook path of the request, or else the bot with the secret token
+// of the request. Requests that match no bot are answered with 404 Not Found, and requests routed
+// by path without the secret token of the bot with 403 Forbidden.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	secret := req.Header.Get(SecretTokenHeader)
+
+	r.mu.RLock()
+	e, ok := r.byPath[req.URL.Path]
+
+	if !ok && secret != "" {
+		e, ok = r.bySecret[secret]
+	}
+
+	r.mu.RUnlock()
+
+	if !ok {
+		http.NotFound(w, req)
+
+		return
+	}
+
+	if e.WebhookSecret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(e.WebhookSecret)) != 1 {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+
+		return
+	}
+
+	r.Dispatcher.WebhookHandler(e.Bot).ServeHTTP(w, req)
+}
+
+// Run starts the bots and blocks until ctx is done or one of them fails. Bots without Me are first
+// identified with getMe. The pollers of the bots are run and the webhooks are served by Server.
+//
+// When ctx is done or a poller or the server fails, Run stops them all: the server stops accepting
+// requests, the pollers stop fetching updates, the updates held back by the middlewares of the dispatcher
+// are flushed, the OnShutdown functions are called, and the updates already received are processed
+// within ShutdownTimeout before the dispatcher is shut down. Run returns the first error, or nil if
+// the bots were stopped by ctx and all their updates were processed.
+func (r *Registry) Run(ctx context.Context) error {
+	entries := r.Entries()
+
+	for _, e := range entries {
+		if e.Bot.Me != nil {
+			continue
+		}
+
+		me, err := e.Bot.GetMe()
+
+		if err != nil {
+			return fmt.Errorf("tgbot: cannot identify bot %q: %v", e.Name, err)
+		}
+
+		e.Bot.Me = me
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(entries)+1)
+
+	for _, e := range entries {
+		if e.Poller == nil {
+			continue
+		}
+
+		p := e.Poller
+
+		if p.OffsetStore == nil && r.Storage != nil {
+			p.OffsetStore = NewStorageOffsetStore(r.Storage, "offset/"+botId(p.Bot))
+		}
+
+		wg.Add(1)
+
+		go func(name string) {
+			defer wg.Done()
+
+			if err := p.Run(runCtx, r.Dispatcher); err != nil {
+				errs <- fmt.Errorf("tgbot: bot %q: %v", name, err)
+				cancel()
+			}
+		}(e.Name)
+	}
+
+	if r.Server != nil {
+		if r.Server.Handler == nil {
+			r.Server.Handler = r
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := r.Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				errs <- err
+				cancel()
+			}
+		}()
+	}
+
+	<-runCtx.Done()
+
+	timeout := r.ShutdownTimeout
+
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), timeout)
+	defer shutdownCancel()
+
+	var err error
+
+	if r.Server != nil {
+		err = r.Server.Shutdown(shutdownCtx)
+	}
+
+	r.Dispatcher.Flush()
+	wg.Wait()
+
+	for _, f := range r.OnShutdown {
+		if fErr := f(shutdownCtx); err == nil {
+			err = fErr
+		}
+	}
+
+	if dErr := r.Dispatcher.Shutdown(shutdownCtx); err == nil {
+		err = dErr
+	}
+
+	close(errs)
+
+	if first, ok := <-errs; ok {
+		return first
+	}
+
+	return err
+}
@@ -0,0 +1,66 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+	"github.com/modern-dev/tgbot-go/tgbottest"
+)
+
+func TestRegistryRunFlushesBeforeShutdown(t *testing.T) {
+	rec := &albumRecorder{}
+	mc := &tgbot.MediaGroupCollector{QuietPeriod: time.Hour}
+	d := tgbot.NewDispatcher(albumRouter(mc, rec), 2, 4)
+	bot := &tgbot.Bot{Token: tgbottest.Token}
+
+	for _, u := range []*tgbot.Update{albumUpdate(1, 11, "g"), albumUpdate(2, 12, "g")} {
+		if err := d.Dispatch(context.Background(), bot, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hookErr := errors.New("hook failed")
+	var calls []string
+	reg := tgbot.NewRegistry(d)
+	reg.ShutdownTimeout = 5 * time.Second
+	reg.OnShutdown = []func(ctx context.Context) error{
+		func(ctx context.Context) error {
+			calls = append(calls, "first")
+
+			if err := d.Dispatch(ctx, bot, &tgbot.Update{UpdateId: 3}); err != nil {
+				t.Errorf("dispatcher shut down before the hooks: %v", err)
+			}
+
+			return hookErr
+		},
+		func(ctx context.Context) error {
+			calls = append(calls, "second")
+
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := reg.Run(ctx); err != hookErr {
+		t.Errorf("Run = %v, want the error of the hook", err)
+	}
+
+	if want := []string{"first", "second"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("hooks called %v, want %v", calls, want)
+	}
+
+	if albums, want := rec.get(), [][]int{{11, 12}}; !reflect.DeepEqual(albums, want) {
+		t.Errorf("albums = %v, want %v", albums, want)
+	}
+}
@@ -0,0 +1,128 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"sort"
+	"strings"
+)
+
+// TextBuilder composes formatted text as plain text and message entities, which are sent with
+// SendMessageOptions.Entities or SendPhotoOptions.CaptionEntities instead of a parse mode,
+// so the text needs no escaping:
+//
+//	b := &TextBuilder{}
+//	b.Bold("Order #42").Text(" is ready, ").Link("track it", "https://example.com/42")
+//	bot.SendMessage(chatId, b.String(), &SendMessageOptions{Entities: b.Entities()})
+//
+// The zero value is an empty builder.
+type TextBuilder struct {
+	text     strings.Builder
+	length   int
+	entities []MessageEntity
+}
+
+// Text appends plain text.
+func (b *TextBuilder) Text(s string) *TextBuilder {
+	b.text.WriteString(s)
+	b.length += Utf16Len(s)
+
+	return b
+}
+
+// Entity appends s formatted with the entity. The offset and the length of e are set by the builder.
+func (b *TextBuilder) Entity(e MessageEntity, s string) *TextBuilder {
+	return b.Wrap(e, func(b *TextBuilder) {
+		b.Text(s)
+	})
+}
+
+// Wrap appends the text built by build formatted with the entity, e.g. to make bold text with
+// an italic part. The offset and the length of e are set by the builder.
+func (b *TextBuilder) Wrap(e MessageEntity, build func(b *TextBuilder)) *TextBuilder {
+	start := b.length
+	build(b)
+
+	if b.length > start {
+		e.Offset, e.Length = start, b.length-start
+		b.entities = append(b.entities, e)
+	}
+
+	return b
+}
+
+// Bold appends bold text.
+func (b *TextBuilder) Bold(s string) *TextBuilder {
+	return b.Entity(MessageEntity{Type: EntityBold}, s)
+}
+
+// Italic appends italic text.
+func (b *TextBuilder) Italic(s string) *TextBuilder {
+	return b.Entity(MessageEntity{Type: EntityItalic}, s)
+}
+
+// Underline appends underlined text.
+func (b *TextBuilder) Underline(s string) *TextBuilder {
+	return b.Entity(MessageEntity{Type: EntityUnderline}, s)
+}
+
+// Strikethrough appends strikethrough text.
+func (b *TextBuilder) Strikethrough(s string) *TextBuilder {
+	return b.Entity(MessageEntity{Type: EntityStrikethrough}, s)
+}
+
+// Code appends a monowidth string.
+func (b *TextBuilder) Code(s string) *TextBuilder {
+	return b.Entity(MessageEntity{Type: EntityCode}, s)
+}
+
+// Pre appends a monowidth block of code in the programming language, which may be empty.
+func (b *TextBuilder) Pre(s, language string) *TextBuilder {
+	return b.Entity(MessageEntity{Type: EntityPre, Language: language}, s)
+}
+
+// Link appends text that opens the URL.
+func (b *TextBuilder) Link(s, url string) *TextBuilder {
+	return b.Entity(MessageEntity{Type: EntityTextLink, Url: url}, s)
+}
+
+// Mention appends text that mentions the user, which works for users without usernames.
+func (b *TextBuilder) Mention(s string, user *User) *TextBuilder {
+	return b.Entity(MessageEntity{Type: EntityTextMention, User: user}, s)
+}
+
+// String returns the plain text.
+func (b *TextBuilder) String() string {
+	return b.text.String()
+}
+
+// Len returns the length of the text in UTF-16 code units, the unit of Telegram's length limits.
+func (b *TextBuilder) Len() int {
+	return b.length
+}
+
+// Entities returns the entities of the text, ordered by offset with enclosing entities first.
+func (b *TextBuilder) Entities() []MessageEntity {
+	entities := make([]MessageEntity, len(b.entities))
+	copy(entities, b.entities)
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		if entities[i].Offset != entities[j].Offset {
+			return entities[i].Offset < entities[j].Offset
+		}
+
+		return entities[i].Length > entities[j].Length
+	})
+
+	return entities
+}
+
+// Reset empties the builder.
+func (b *TextBuilder) Reset() {
+	b.text.Reset()
+	b.length = 0
+	b.entities = nil
+}
@@ -0,0 +1,226 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import "errors"
+
+// ErrNotHandled is returned by a Router when no handler processed the update. A handler may return it
+// to decline an update it was routed, so the router tries the handlers registered after it.
+var ErrNotHandled = errors.New("tgbot: update not handled")
+
+// Handler processes updates.
+type Handler interface {
+	HandleUpdate(c *Context) error
+}
+
+// HandlerFunc is an adapter to allow the use of ordinary functions as handlers.
+type HandlerFunc func(c *Context) error
+
+// HandleUpdate calls f(c).
+func (f HandlerFunc) HandleUpdate(c *Context) error {
+	return f(c)
+}
+
+// Route is a handler registered in a Router.
+type Route struct {
+	kind      UpdateKind
+	filters   []Filter
+	handler   Handler
+	continues bool
+	mws       []Middleware
+}
+
+// Fallthrough makes the router keep looking for matching routes after the handler of this route
+// processed an update. By default, routing stops at the first route that handles the update.
+func (rt *Route) Fallthrough() *Route {
+	rt.continues = true
+
+	return rt
+}
+
+// Use adds middlewares that wrap the handler of this route.
+func (rt *Route) Use(mws ...Middleware) *Route {
+	rt.mws = append(rt.mws, mws...)
+
+	return rt
+}
+
+func (rt *Route) match(c *Context) bool {
+	if rt.kind != UpdateAny && rt.kind != c.Update.Kind() {
+		return false
+	}
+
+	for _, f := range rt.filters {
+		if !f(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Router routes updates to handlers by update kind and filters. Routes are evaluated in registration
+// order: the first route whose kind and filters match processes the update, unless it is marked
+// with Fallthrough or its handler returns ErrNotHandled.
+type Router struct {
+	routes []*Route
+	mws    []Middleware
+}
+
+// NewRouter returns an empty Router.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle registers the handler for updates of the kind that match all filters.
+func (r *Router) Handle(kind UpdateKind, h Handler, filters ...Filter) *Route {
+	rt := &Route{
+		kind:    kind,
+		filters: filters,
+		handler: h,
+	}
+
+	r.routes = append(r.routes, rt)
+
+	return rt
+}
+
+// Use adds middlewares that wrap every update passed to the router, whether a route matches it or not.
+// See Middleware for the order in which middlewares run.
+func (r *Router) Use(mws ...Middleware) {
+	r.mws = append(r.mws, mws...)
+}
+
+// Group returns a router nested in r that receives the updates matching all filters. The group is
+// evaluated in the position it was created in, and routing continues after it if none of its routes
+// processed the update. Middlewares added to the group wrap only the updates routed to it.
+func (r *Router) Group(filters ...Filter) *Router {
+	group := NewRouter()
+	r.Handle(UpdateAny, group, filters...)
+
+	return group
+}
+
+// OnUpdate registers the handler for updates of every kind.
+func (r *Router) OnUpdate(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdateAny, h, filters...)
+}
+
+// OnMessage registers the handler for new messages.
+func (r *Router) OnMessage(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdateMessage, h, filters...)
+}
+
+// OnEditedMessage registers the handler for edited messages.
+func (r *Router) OnEditedMessage(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdateEditedMessage, h, filters...)
+}
+
+// OnChannelPost registers the handler for new channel posts.
+func (r *Router) OnChannelPost(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdateChannelPost, h, filters...)
+}
+
+// OnEditedChannelPost registers the handler for edited channel posts.
+func (r *Router) OnEditedChannelPost(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdateEditedChannelPost, h, filters...)
+}
+
+// OnCallbackQuery registers the handler for callback queries.
+func (r *Router) OnCallbackQuery(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdateCallbackQuery, h, filters...)
+}
+
+// OnInlineQuery registers the handler for inline queries.
+func (r *Router) OnInlineQuery(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdateInlineQuery, h, filters...)
+}
+
+// OnChosenInlineResult registers the handler for chosen inline results.
+func (r *Router) OnChosenInlineResult(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdateChosenInlineResult, h, filters...)
+}
+
+// OnPoll registers the handler for poll state updates.
+func (r *Router) OnPoll(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdatePoll, h, filters...)
+}
+
+// OnPollAnswer registers the handler for poll answers.
+func (r *Router) OnPollAnswer(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdatePollAnswer, h, filters...)
+}
+
+// OnAlbum registers the handler for albums made by a MediaGroupCollector.
+func (r *Router) OnAlbum(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdateAlbum, h, filters...)
+}
+
+// OnShippingQuery registers the handler for shipping queries.
+func (r *Router) OnShippingQuery(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdateShippingQuery, h, filters...)
+}
+
+// OnPreCheckoutQuery registers the handler for pre-checkout queries.
+func (r *Router) OnPreCheckoutQuery(h HandlerFunc, filters ...Filter) *Route {
+	return r.Handle(UpdatePreCheckoutQuery, h, filters...)
+}
+
+// OnPayments routes shipping and pre-checkout queries to the payment handler. The handler's Bot
+// is ignored in favour of the bot that received the update.
+func (r *Router) OnPayments(ph *PaymentHandler) {
+	r.OnShippingQuery(func(c *Context) error {
+		h := *ph
+		h.Bot = c.Bot
+
+		return h.HandleShippingQuery(c.Update.ShippingQuery)
+	})
+
+	r.OnPreCheckoutQuery(func(c *Context) error {
+		h := *ph
+		h.Bot = c.Bot
+
+		return h.HandlePreCheckoutQuery(c.Update.PreCheckoutQuery)
+	})
+}
+
+// HandleUpdate routes the update to the matching handlers. It returns ErrNotHandled
+// if no handler processed the update.
+func (r *Router) HandleUpdate(c *Context) error {
+	return chain(HandlerFunc(r.route), r.mws).HandleUpdate(c)
+}
+
+func (r *Router) route(c *Context) error {
+	handled := false
+
+	for _, rt := range r.routes {
+		if !rt.match(c) {
+			continue
+		}
+
+		err := chain(rt.handler, rt.mws).HandleUpdate(c)
+
+		if err == ErrNotHandled {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !rt.continues {
+			return nil
+		}
+
+		handled = true
+	}
+
+	if !handled {
+		return ErrNotHandled
+	}
+
+	return nil
+}
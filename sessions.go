@@ -0,0 +1,97 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Sessions keeps per-user data as JSON in a Storage.
+type Sessions struct {
+	Storage Storage
+	// TTL is the time a session is kept after it was last saved, forever if zero.
+	TTL time.Duration
+}
+
+// NewSessions returns sessions kept in the storage for ttl after they were last saved.
+func NewSessions(storage Storage, ttl time.Duration) *Sessions {
+	return &Sessions{
+		Storage: storage,
+		TTL:     ttl,
+	}
+}
+
+// Load unmarshals the session of the user into v and reports whether there is one.
+func (s *Sessions) Load(userId int, v interface{}) (bool, error) {
+	data, ok, err := s.Storage.Get(sessionKey(userId))
+
+	if err != nil || !ok {
+		return false, err
+	}
+
+	return true, json.Unmarshal(data, v)
+}
+
+// Save stores v as the session of the user.
+func (s *Sessions) Save(userId int, v interface{}) error {
+	data, err := json.Marshal(v)
+
+	if err != nil {
+		return err
+	}
+
+	return s.Storage.Set(sessionKey(userId), data, s.TTL)
+}
+
+// Delete removes the session of the user.
+func (s *Sessions) Delete(userId int) error {
+	return s.Storage.Delete(sessionKey(userId))
+}
+
+// Update loads the session of the user into v, calls fn to modify it and saves v. If the session
+// was changed by someone else in the meantime, v is reloaded and fn is called again. If the user
+// has no session, v is left as it is before fn is called.
+func (s *Sessions) Update(userId int, v interface{}, fn func() error) error {
+	key := sessionKey(userId)
+
+	for {
+		old, ok, err := s.Storage.Get(key)
+
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			if err = json.Unmarshal(old, v); err != nil {
+				return err
+			}
+		} else {
+			old = nil
+		}
+
+		if err = fn(); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(v)
+
+		if err != nil {
+			return err
+		}
+
+		swapped, err := s.Storage.CompareAndSwap(key, old, data, s.TTL)
+
+		if err != nil || swapped {
+			return err
+		}
+	}
+}
+
+func sessionKey(userId int) string {
+	return "session/" + strconv.Itoa(userId)
+}
@@ -0,0 +1,268 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxMessageLength is the maximum length of the text of a message in UTF-16 code units.
+	MaxMessageLength = 4096
+	// MaxCaptionLength is the maximum length of a caption in UTF-16 code units.
+	MaxCaptionLength = 1024
+)
+
+// TextChunk is a part of a text split by SplitText, with the entities of the part.
+type TextChunk struct {
+	Text     string
+	Entities []MessageEntity
+}
+
+// SplitText splits text formatted with the entities into chunks of at most limit UTF-16 code units.
+// Chunks end at a paragraph break if possible, otherwise at a line break, a space, or, for text
+// without whitespace, anywhere between two characters. Breaks inside mentions, hashtags, URLs and
+// similar entities are avoided. Entities crossing a break are continued in the next chunk, and
+// the whitespace at the break is dropped. A character longer than the limit, such as an emoji with
+// a limit of one, makes a chunk of its own. SplitText panics if limit is less than one.
+func SplitText(text string, entities []MessageEntity, limit int) []TextChunk {
+	if limit < 1 {
+		panic(fmt.Sprintf("tgbot: SplitText limit must be positive, got %d", limit))
+	}
+
+	var chunks []TextChunk
+
+	for {
+		if Utf16Len(text) <= limit {
+			return append(chunks, TextChunk{Text: text, Entities: entities})
+		}
+
+		cut, next := splitPoint(text, entities, limit)
+		chunk, chunkEntities := SliceText(text, entities, 0, cut)
+		chunks = append(chunks, TextChunk{Text: chunk, Entities: chunkEntities})
+
+		if next == len(text) {
+			return chunks
+		}
+
+		text, entities = SliceText(text, entities, next, len(text))
+	}
+}
+
+// splitPoint returns the byte offset where the first chunk of text ends and the byte offset where
+// the next chunk starts. The first chunk holds at least one character.
+func splitPoint(text string, entities []MessageEntity, limit int) (cut, next int) {
+	// end is the byte offset of the longest prefix within the limit, which never splits a character
+	// and so never splits a surrogate pair.
+	end := utf16ToByteOffset(text, limit)
+
+	if Utf16Len(text[:end]) > limit {
+		_, n := utf8.DecodeLastRuneInString(text[:end])
+		end -= n
+	}
+
+	if end == 0 {
+		_, n := utf8.DecodeRuneInString(text)
+
+		return n, n
+	}
+
+	atomic := atomicRanges(text, entities)
+
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		for i := strings.LastIndex(text[:end+len(sep)-1], sep); i > 0; i = strings.LastIndex(text[:i], sep) {
+			if insideRange(atomic, i) {
+				continue
+			}
+
+			if cut, next := trimBreak(text, i, i+len(sep)); cut > 0 {
+				return cut, next
+			}
+		}
+	}
+
+	for i := end; i > 0; {
+		if !insideRange(atomic, i) {
+			return i, i
+		}
+
+		_, n := utf8.DecodeLastRuneInString(text[:i])
+		i -= n
+	}
+
+	return end, end
+}
+
+// trimBreak extends the break between cut and next over the surrounding whitespace.
+func trimBreak(text string, cut, next int) (int, int) {
+	for cut > 0 {
+		r, n := utf8.DecodeLastRuneInString(text[:cut])
+
+		if !unicode.IsSpace(r) {
+			break
+		}
+
+		cut -= n
+	}
+
+	for next < len(text) {
+		r, n := utf8.DecodeRuneInString(text[next:])
+
+		if !unicode.IsSpace(r) {
+			break
+		}
+
+		next += n
+	}
+
+	return cut, next
+}
+
+// atomicRanges returns the byte ranges of the entities that should not be split.
+func atomicRanges(text string, entities []MessageEntity) [][2]int {
+	var ranges [][2]int
+
+	for _, e := range entities {
+		switch e.Type {
+		case EntityMention, EntityHashtag, EntityCashtag, EntityBotCommand, EntityUrl, EntityEmail,
+			EntityPhoneNumber:
+			start, end := e.Range(text)
+			ranges = append(ranges, [2]int{start, end})
+		}
+	}
+
+	return ranges
+}
+
+func insideRange(ranges [][2]int, i int) bool {
+	for _, r := range ranges {
+		if r[0] < i && i < r[1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formattedText returns the plain text and the entities of text formatted with the parse mode
+// or the entities.
+func formattedText(text, parseMode string, entities []MessageEntity) (string, []MessageEntity, error) {
+	switch parseMode {
+	case "":
+		return text, entities, nil
+	case ParseModeHTML:
+		return ParseHTML(text)
+	case ParseModeMarkdownV2:
+		return ParseMarkdownV2(text)
+	}
+
+	return "", nil, fmt.Errorf("tgbot: text formatted with parse mode %s cannot be split", parseMode)
+}
+
+// SendLongMessage sends text of any length as a sequence of messages of at most MaxMessageLength,
+// split by SplitText. Text formatted with the HTML or MarkdownV2 parse mode is converted to entities,
+// so markup is never cut and formatting continues across messages. Only the first message replies
+// to ReplyToMessageId and only the last one has the ReplyMarkup. On success, the sent messages are returned
+// in order; on failure, the messages sent before the error are returned with it.
+func (bot *Bot) SendLongMessage(chatId, text string, opts *SendMessageOptions) ([]*Message, error) {
+	if opts == nil {
+		opts = &SendMessageOptions{}
+	}
+
+	plain, entities, err := formattedText(text, opts.ParseMode, opts.Entities)
+
+	if err != nil && Utf16Len(text) > MaxMessageLength {
+		return nil, err
+	}
+
+	if err != nil || Utf16Len(plain) <= MaxMessageLength {
+		msg, err := bot.SendMessage(chatId, text, opts)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []*Message{msg}, nil
+	}
+
+	return bot.sendChunks(chatId, SplitText(plain, entities, MaxMessageLength), opts, nil)
+}
+
+// sendChunks sends the chunks as messages. The chunks follow the messages already sent.
+func (bot *Bot) sendChunks(chatId string, chunks []TextChunk, opts *SendMessageOptions, sent []*Message) ([]*Message, error) {
+	for i, chunk := range chunks {
+		chunkOpts := *opts
+		chunkOpts.ParseMode = ""
+		chunkOpts.Entities = chunk.Entities
+
+		if len(sent) > 0 {
+			chunkOpts.ReplyToMessageId = 0
+		}
+
+		if i < len(chunks)-1 {
+			chunkOpts.ReplyMarkup = ""
+		}
+
+		msg, err := bot.SendMessage(chatId, chunk.Text, &chunkOpts)
+
+		if err != nil {
+			return sent, err
+		}
+
+		sent = append(sent, msg)
+	}
+
+	return sent, nil
+}
+
+// SendPhotoWithLongCaption sends a photo with a caption of any length. If the caption is longer
+// than MaxCaptionLength, the photo gets the first part of it, split as by SplitText, and the rest
+// is sent in messages that follow the photo. Only the last message has the ReplyMarkup.
+// On success, the sent messages are returned in order; on failure, the messages sent before
+// the error are returned with it.
+func (bot *Bot) SendPhotoWithLongCaption(chatId string, photo InputFile, opts *SendPhotoOptions) ([]*Message, error) {
+	if opts == nil {
+		opts = &SendPhotoOptions{}
+	}
+
+	plain, entities, err := formattedText(opts.Caption, opts.ParseMode, opts.CaptionEntities)
+
+	if err != nil && Utf16Len(opts.Caption) > MaxCaptionLength {
+		return nil, err
+	}
+
+	photoOpts := *opts
+	var rest string
+	var restEntities []MessageEntity
+
+	if err == nil && Utf16Len(plain) > MaxCaptionLength {
+		cut, next := splitPoint(plain, entities, MaxCaptionLength)
+		photoOpts.Caption, photoOpts.CaptionEntities = SliceText(plain, entities, 0, cut)
+		photoOpts.ParseMode = ""
+		photoOpts.ReplyMarkup = ""
+		rest, restEntities = SliceText(plain, entities, next, len(plain))
+	}
+
+	msg, err := bot.SendPhoto(chatId, photo, &photoOpts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sent := []*Message{msg}
+
+	if rest == "" {
+		return sent, nil
+	}
+
+	return bot.sendChunks(chatId, SplitText(rest, restEntities, MaxMessageLength), &SendMessageOptions{
+		DisableNotification: opts.DisableNotification,
+		ReplyMarkup:         opts.ReplyMarkup,
+	}, sent)
+}
@@ -0,0 +1,90 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"reflect"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+func chunkTexts(chunks []tgbot.TextChunk) []string {
+	texts := make([]string, len(chunks))
+
+	for i, c := range chunks {
+		texts[i] = c.Text
+	}
+
+	return texts
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		entities []tgbot.MessageEntity
+		limit    int
+		want     []string
+	}{
+		{name: "within limit", text: "hello", limit: 5, want: []string{"hello"}},
+		{name: "empty", text: "", limit: 1, want: []string{""}},
+		{name: "limit of one", text: "abc", limit: 1, want: []string{"a", "b", "c"}},
+		{name: "surrogate pairs with limit of one", text: "😀😀", limit: 1, want: []string{"😀", "😀"}},
+		{name: "surrogate pairs with limit of two", text: "😀😀", limit: 2, want: []string{"😀", "😀"}},
+		{name: "surrogate pairs with limit of three", text: "😀😀", limit: 3, want: []string{"😀", "😀"}},
+		{name: "surrogate pairs within limit", text: "😀😀", limit: 4, want: []string{"😀😀"}},
+		{name: "pair is not split", text: "a😀b", limit: 2, want: []string{"a", "😀", "b"}},
+		{name: "paragraph break", text: "one two\n\nthree", limit: 12, want: []string{"one two", "three"}},
+		{name: "line break", text: "one two\nthree four", limit: 12, want: []string{"one two", "three four"}},
+		{name: "space", text: "one two three", limit: 9, want: []string{"one two", "three"}},
+		{name: "trailing whitespace", text: "ab    ", limit: 3, want: []string{"ab"}},
+		{
+			name:     "hashtag is not split",
+			text:     "ab#tag",
+			entities: []tgbot.MessageEntity{{Type: tgbot.EntityHashtag, Offset: 2, Length: 4}},
+			limit:    4,
+			want:     []string{"ab", "#tag"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := tgbot.SplitText(tt.text, tt.entities, tt.limit)
+
+			if got := chunkTexts(chunks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitText(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTextContinuesEntities(t *testing.T) {
+	bold := []tgbot.MessageEntity{{Type: tgbot.EntityBold, Offset: 0, Length: 11}}
+	chunks := tgbot.SplitText("hello world", bold, 6)
+	want := []tgbot.TextChunk{
+		{Text: "hello", Entities: []tgbot.MessageEntity{{Type: tgbot.EntityBold, Offset: 0, Length: 5}}},
+		{Text: "world", Entities: []tgbot.MessageEntity{{Type: tgbot.EntityBold, Offset: 0, Length: 5}}},
+	}
+
+	if !reflect.DeepEqual(chunks, want) {
+		t.Errorf("SplitText = %+v, want %+v", chunks, want)
+	}
+}
+
+func TestSplitTextPanicsWithoutPositiveLimit(t *testing.T) {
+	for _, limit := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("SplitText with limit %d did not panic", limit)
+				}
+			}()
+
+			tgbot.SplitText("text", nil, limit)
+		}()
+	}
+}
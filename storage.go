@@ -0,0 +1,172 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// Storage is a key-value store for conversation states, sessions and other data that should
+// outlive a single update. Implementations must be safe for concurrent use.
+type Storage interface {
+	// Get returns the value stored under the key and whether there is one.
+	Get(key string) ([]byte, bool, error)
+	// Set stores the value under the key. The value expires after ttl, or never if ttl is zero.
+	Set(key string, value []byte, ttl time.Duration) error
+	// Delete removes the value stored under the key, if any.
+	Delete(key string) error
+	// CompareAndSwap stores the new value under the key only if the current value equals old,
+	// with nil meaning no value, and reports whether it did. A nil new value deletes the key.
+	CompareAndSwap(key string, old, new []byte, ttl time.Duration) (bool, error)
+}
+
+// memoryEntry is a value stored in a MemoryStorage.
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+// MemoryStorage is a Storage that keeps values in memory. Its data is lost when the process exits.
+type MemoryStorage struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	writes  int
+}
+
+// NewMemoryStorage returns an empty MemoryStorage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		entries: make(map[string]*memoryEntry),
+	}
+}
+
+func (s *MemoryStorage) Get(key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.get(key, time.Now())
+
+	return value, ok, nil
+}
+
+func (s *MemoryStorage) Set(key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set(key, value, ttl)
+
+	return nil
+}
+
+func (s *MemoryStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+func (s *MemoryStorage) CompareAndSwap(key string, old, new []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	swapped, _ := s.compareAndSwap(key, old, new, ttl)
+
+	return swapped, nil
+}
+
+// compareAndSwap swaps the value of the key and returns the new entry, or nil if the key was deleted.
+// s.mu must be held.
+func (s *MemoryStorage) compareAndSwap(key string, old, new []byte, ttl time.Duration) (bool, *memoryEntry) {
+	current, ok := s.get(key, time.Now())
+
+	if ok != (old != nil) || !bytes.Equal(current, old) {
+		return false, nil
+	}
+
+	if new == nil {
+		delete(s.entries, key)
+
+		return true, nil
+	}
+
+	return true, s.set(key, new, ttl)
+}
+
+func (s *MemoryStorage) get(key string, now time.Time) ([]byte, bool) {
+	e, ok := s.entries[key]
+
+	if !ok {
+		return nil, false
+	}
+
+	if e.expired(now) {
+		delete(s.entries, key)
+
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+// set stores the value and returns its entry. s.mu must be held.
+func (s *MemoryStorage) set(key string, value []byte, ttl time.Duration) *memoryEntry {
+	e := &memoryEntry{value: append([]byte{}, value...)}
+
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+
+	s.entries[key] = e
+	s.writes++
+
+	// Expired entries are removed when read; sweep the rest from time to time.
+	if s.writes%1024 == 0 {
+		now := time.Now()
+
+		for k, old := range s.entries {
+			if old.expired(now) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	return e
+}
+
+type prefixStorage struct {
+	storage Storage
+	prefix  string
+}
+
+// PrefixStorage returns a Storage that keeps its values in s under keys starting with the prefix,
+// so that several bots or components can share a storage without their keys colliding.
+func PrefixStorage(s Storage, prefix string) Storage {
+	return &prefixStorage{storage: s, prefix: prefix}
+}
+
+func (s *prefixStorage) Get(key string) ([]byte, bool, error) {
+	return s.storage.Get(s.prefix + key)
+}
+
+func (s *prefixStorage) Set(key string, value []byte, ttl time.Duration) error {
+	return s.storage.Set(s.prefix+key, value, ttl)
+}
+
+func (s *prefixStorage) Delete(key string) error {
+	return s.storage.Delete(s.prefix + key)
+}
+
+func (s *prefixStorage) CompareAndSwap(key string, old, new []byte, ttl time.Duration) (bool, error) {
+	return s.storage.CompareAndSwap(s.prefix+key, old, new, ttl)
+}
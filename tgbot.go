@@ -5,9 +5,34 @@
 
 package tgbot
 
+import (
+	"fmt"
+	"net/http"
+)
+
 type Bot struct {
 	Token string
-	Me *User
+	Me    *User
+	// ApiEndpoint is the format of the URL of Bot API methods with the token and the method name,
+	// ApiUrl if empty. Set it to use a local Bot API server or a fake server in tests.
+	ApiEndpoint string
+	// FileEndpoint is the format of the URL of files with the token and the file path, FileUrl if empty.
+	FileEndpoint string
+	// Client sends the requests of the bot, http.DefaultClient if nil.
+	Client *http.Client
+	// Logger traces the requests of the bot: successful requests at the debug level, requests answered
+	// with an error at the warn level and failed requests at the error level. Optional.
+	Logger Logger
+	// LogPayloads adds the parameters and the responses of requests to the log records, truncated
+	// to PayloadLogLimit bytes. The token is redacted, but other secrets in payloads are not.
+	LogPayloads bool
+	// PayloadLogLimit is the number of bytes of a payload logged, DefaultPayloadLogLimit if zero.
+	PayloadLogLimit int
+	// Instrumentation observes the requests of the bot, e.g. to collect Metrics. Optional.
+	Instrumentation Instrumentation
+	// RateLimiter delays the requests of the bot to stay within the limits of the Bot API, e.g. a Limiter
+	// shared by the bots of a service. Optional.
+	RateLimiter RateLimiter
 }
 
 func NewBot(token string) (*Bot, error) {
@@ -25,3 +50,31 @@ func NewBot(token string) (*Bot, error) {
 
 	return bot, nil
 }
+
+func (bot *Bot) client() *http.Client {
+	if bot.Client != nil {
+		return bot.Client
+	}
+
+	return http.DefaultClient
+}
+
+func (bot *Bot) methodUrl(method string) string {
+	endpoint := bot.ApiEndpoint
+
+	if endpoint == "" {
+		endpoint = ApiUrl
+	}
+
+	return fmt.Sprintf(endpoint, bot.Token, method)
+}
+
+func (bot *Bot) fileUrl(filePath string) string {
+	endpoint := bot.FileEndpoint
+
+	if endpoint == "" {
+		endpoint = FileUrl
+	}
+
+	return fmt.Sprintf(endpoint, bot.Token, filePath)
+}
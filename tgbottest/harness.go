@@ -0,0 +1,274 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbottest
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+// Harness runs a handler on updates made by simulated users, one at a time, and collects the requests
+// the handler makes to a fake server:
+//
+//	h := tgbottest.NewHarness(t, router)
+//	alice := h.NewUser("Alice")
+//	menu := alice.Sends("/start").LastSent()
+//	alice.Taps(menu, "Settings").LastSent()
+//
+// Each action returns after the update was processed by a dispatcher, so its Result holds all
+// the requests made by the handler.
+type Harness struct {
+	T          testing.TB
+	Server     *Server
+	Bot        *tgbot.Bot
+	Dispatcher *tgbot.Dispatcher
+
+	mu         sync.Mutex
+	errs       []error
+	nextUserId int
+	nextChatId int64
+	nextId     int
+}
+
+// NewHarness starts a fake server and a dispatcher running the handler. They are shut down
+// when the test ends.
+func NewHarness(t testing.TB, handler tgbot.Handler) *Harness {
+	h := &Harness{
+		T:          t,
+		Server:     NewServer(),
+		Dispatcher: tgbot.NewDispatcher(handler, 1, 1),
+		nextUserId: 1000,
+		nextChatId: -1001000,
+	}
+
+	h.Bot = h.Server.NewBot()
+	h.Dispatcher.OnError = func(c *tgbot.Context, err error) {
+		h.mu.Lock()
+		h.errs = append(h.errs, err)
+		h.mu.Unlock()
+	}
+
+	t.Cleanup(func() {
+		h.Dispatcher.Shutdown(context.Background())
+		h.Server.Close()
+	})
+
+	return h
+}
+
+// Errors returns the errors returned by the handler so far, except ErrNotHandled and ErrForbidden.
+func (h *Harness) Errors() []error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]error(nil), h.errs...)
+}
+
+// Result holds the requests made by the handler while processing an update.
+type Result struct {
+	// Update is the processed update.
+	Update tgbot.Update
+	// Calls are the requests made to the server.
+	Calls []Call
+	// Sent are the messages sent by the bot.
+	Sent []*tgbot.Message
+	// Errors are the errors returned by the handler.
+	Errors []error
+
+	t testing.TB
+}
+
+// Call returns the first request for the method, or nil if there is none.
+func (r *Result) Call(method string) *Call {
+	for i := range r.Calls {
+		if r.Calls[i].Method == method {
+			return &r.Calls[i]
+		}
+	}
+
+	return nil
+}
+
+// LastSent returns the last message sent by the bot. The test fails if the bot sent no messages.
+func (r *Result) LastSent() *tgbot.Message {
+	r.t.Helper()
+
+	if len(r.Sent) == 0 {
+		r.t.Fatalf("tgbottest: no messages sent in response to update %d", r.Update.UpdateId)
+	}
+
+	return r.Sent[len(r.Sent)-1]
+}
+
+// Run processes the update and returns the requests made by the handler. The update gets the next
+// identifier if it has none.
+func (h *Harness) Run(u tgbot.Update) *Result {
+	h.T.Helper()
+
+	h.Server.mu.Lock()
+	h.Server.receive(&u)
+	calls, sent := len(h.Server.calls), len(h.Server.sent)
+	h.Server.mu.Unlock()
+
+	h.mu.Lock()
+	errs := len(h.errs)
+	h.mu.Unlock()
+
+	if err := h.Dispatcher.DispatchSync(context.Background(), h.Bot, &u); err != nil {
+		h.T.Fatalf("tgbottest: cannot dispatch update %d: %v", u.UpdateId, err)
+	}
+
+	r := &Result{Update: u, t: h.T}
+
+	h.Server.mu.Lock()
+	r.Calls = append(r.Calls, h.Server.calls[calls:]...)
+	r.Sent = append(r.Sent, h.Server.sent[sent:]...)
+	h.Server.mu.Unlock()
+
+	h.mu.Lock()
+	r.Errors = append(r.Errors, h.errs[errs:]...)
+	h.mu.Unlock()
+
+	return r
+}
+
+func (h *Harness) newId() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextId++
+
+	return strconv.Itoa(h.nextId)
+}
+
+// User is a simulated user talking to the bot in a chat.
+type User struct {
+	tgbot.User
+	// Chat is the chat the user writes to, the private chat with the bot by default.
+	Chat tgbot.Chat
+
+	h *Harness
+}
+
+// NewUser returns a new user with the first name, writing to the bot in a private chat.
+func (h *Harness) NewUser(firstName string) *User {
+	h.mu.Lock()
+	h.nextUserId++
+	id := h.nextUserId
+	h.mu.Unlock()
+
+	return &User{
+		User: tgbot.User{Id: id, FirstName: firstName, LanguageCode: "en"},
+		Chat: tgbot.Chat{Id: int64(id), Type: "private", FirstName: firstName},
+		h:    h,
+	}
+}
+
+// NewGroup returns a new supergroup with the title, to be joined by users with In.
+func (h *Harness) NewGroup(title string) tgbot.Chat {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextChatId--
+
+	return tgbot.Chat{Id: h.nextChatId, Type: "supergroup", Title: title}
+}
+
+// In returns the user writing to the chat.
+func (u *User) In(chat tgbot.Chat) *User {
+	in := *u
+	in.Chat = chat
+
+	return &in
+}
+
+// Sends sends a text message to the bot. A command at the start of the text gets a bot_command entity.
+func (u *User) Sends(text string) *Result {
+	u.h.T.Helper()
+
+	return u.SendsMessage(textMessage(u.Chat, u.User, text))
+}
+
+// SendsMessage sends the message to the bot. The sender and the chat are set if they are nil.
+func (u *User) SendsMessage(msg *tgbot.Message) *Result {
+	u.h.T.Helper()
+
+	if msg.From == nil {
+		from := u.User
+		msg.From = &from
+	}
+
+	if msg.Chat == nil {
+		chat := u.Chat
+		msg.Chat = &chat
+	}
+
+	return u.h.Run(tgbot.Update{Message: msg})
+}
+
+// Edits changes the text of a message the user sent before.
+func (u *User) Edits(msg *tgbot.Message, text string) *Result {
+	u.h.T.Helper()
+
+	edited := *textMessage(*msg.Chat, u.User, text)
+	edited.MessageId = msg.MessageId
+	edited.Date = msg.Date
+	edited.EditDate = msg.Date + 1
+
+	return u.h.Run(tgbot.Update{EditedMessage: &edited})
+}
+
+// Taps taps the inline keyboard button with the text on the message. The test fails if the message
+// has no such button with callback data.
+func (u *User) Taps(msg *tgbot.Message, buttonText string) *Result {
+	u.h.T.Helper()
+
+	if msg.ReplyMarkup != nil {
+		for _, row := range msg.ReplyMarkup.InlineKeyboard {
+			for _, button := range row {
+				if button.Text == buttonText && button.CallbackData != "" {
+					return u.SendsCallback(msg, button.CallbackData)
+				}
+			}
+		}
+	}
+
+	u.h.T.Fatalf("tgbottest: message %d has no button %q with callback data", msg.MessageId, buttonText)
+
+	return nil
+}
+
+// SendsCallback sends a callback query with the data from a button of the message.
+func (u *User) SendsCallback(msg *tgbot.Message, data string) *Result {
+	u.h.T.Helper()
+
+	from := u.User
+
+	return u.h.Run(tgbot.Update{CallbackQuery: &tgbot.CallbackQuery{
+		Id:           u.h.newId(),
+		From:         &from,
+		Message:      msg,
+		ChatInstance: strconv.FormatInt(msg.Chat.Id, 10),
+		Data:         data,
+	}})
+}
+
+// Queries sends an inline query to the bot.
+func (u *User) Queries(query string) *Result {
+	u.h.T.Helper()
+
+	from := u.User
+
+	return u.h.Run(tgbot.Update{InlineQuery: &tgbot.InlineQuery{
+		Id:    u.h.newId(),
+		From:  &from,
+		Query: query,
+	}})
+}
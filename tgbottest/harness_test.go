@@ -0,0 +1,89 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbottest_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+	"github.com/modern-dev/tgbot-go/tgbottest"
+)
+
+func menuRouter() *tgbot.Router {
+	menu := &tgbot.InlineKeyboardMarkup{InlineKeyboard: [][]tgbot.InlineKeyboardButton{
+		{{Text: "Settings", CallbackData: "settings"}},
+	}}
+
+	router := tgbot.NewRouter()
+	router.OnMessage(func(c *tgbot.Context) error {
+		_, err := c.Reply("Menu", &tgbot.SendMessageOptions{ReplyMarkup: menu.String()})
+
+		return err
+	}, tgbot.Commands("start"))
+	router.OnMessage(func(c *tgbot.Context) error {
+		return errors.New("unknown command")
+	})
+	router.OnCallbackQuery(func(c *tgbot.Context) error {
+		q := c.Update.CallbackQuery
+
+		if _, err := c.Bot.AnswerCallbackQuery(q.Id, nil); err != nil {
+			return err
+		}
+
+		_, err := c.Bot.EditMessageText("Settings",
+			tgbot.ChatMessage(strconv.FormatInt(q.Message.Chat.Id, 10), q.Message.MessageId), nil)
+
+		return err
+	})
+
+	return router
+}
+
+func TestHarnessRunsConversation(t *testing.T) {
+	h := tgbottest.NewHarness(t, menuRouter())
+	alice := h.NewUser("Alice")
+	menu := alice.Sends("/start").LastSent()
+
+	if menu.Text != "Menu" || menu.Chat.Id != alice.Chat.Id {
+		t.Fatalf("menu = %+v", menu)
+	}
+
+	r := alice.Taps(menu, "Settings")
+
+	if r.Call("answerCallbackQuery") == nil || r.Call("editMessageText") == nil || len(r.Sent) != 0 {
+		t.Errorf("requests for the tap = %+v", r.Calls)
+	}
+
+	if messages := h.Server.Messages(alice.Chat.Id); len(messages) != 2 || messages[1].Text != "Settings" {
+		t.Errorf("messages of the chat = %+v", messages)
+	}
+
+	if errs := h.Errors(); len(errs) != 0 {
+		t.Errorf("errors = %v", errs)
+	}
+}
+
+func TestHarnessCollectsErrors(t *testing.T) {
+	h := tgbottest.NewHarness(t, menuRouter())
+	group := h.NewGroup("Friends")
+	bob := h.NewUser("Bob").In(group)
+
+	r := bob.Sends("/stop")
+
+	if len(r.Errors) != 1 || r.Errors[0].Error() != "unknown command" {
+		t.Errorf("errors of the update = %v", r.Errors)
+	}
+
+	if r.Update.Message.Chat.Id != group.Id || r.Update.UpdateId == 0 {
+		t.Errorf("update = %+v", r.Update)
+	}
+
+	if r = bob.Sends("/start"); len(r.Errors) != 0 || len(h.Errors()) != 1 {
+		t.Errorf("errors = %v, %v, want only the first one", r.Errors, h.Errors())
+	}
+}
@@ -0,0 +1,510 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbottest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+// builtin returns the built-in implementation of the method.
+func (s *Server) builtin(r *http.Request, method string) (MethodHandler, bool) {
+	switch method {
+	case "getMe":
+		return func(call *Call) (interface{}, error) {
+			return s.Me, nil
+		}, true
+	case "getUpdates":
+		return func(call *Call) (interface{}, error) {
+			return s.getUpdates(r, call)
+		}, true
+	case "sendMessage":
+		return s.sendMessage, true
+	case "sendPhoto":
+		return s.sendPhoto, true
+	case "sendSticker":
+		return s.sendSticker, true
+	case "getFile":
+		return s.getFile, true
+	case "editMessageText":
+		return s.editMessageText, true
+	case "editMessageCaption":
+		return s.editMessageCaption, true
+	case "answerCallbackQuery":
+		return func(call *Call) (interface{}, error) {
+			return true, nil
+		}, true
+	}
+
+	return nil, false
+}
+
+// getUpdates confirms the updates before the offset and returns the pending ones, waiting up to
+// the timeout for new updates if there are none.
+func (s *Server) getUpdates(r *http.Request, call *Call) (interface{}, error) {
+	offset, _ := strconv.Atoi(call.Params["offset"])
+	limit, _ := strconv.Atoi(call.Params["limit"])
+	timeout, _ := strconv.Atoi(call.Params["timeout"])
+
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+
+	timer := time.NewTimer(time.Duration(timeout) * time.Second)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+
+		if offset > 0 {
+			i := 0
+
+			for i < len(s.updates) && s.updates[i].UpdateId < offset {
+				i++
+			}
+
+			s.updates = s.updates[i:]
+		}
+
+		updates := make([]tgbot.Update, 0, limit)
+
+		for _, u := range s.updates {
+			if len(updates) == limit {
+				break
+			}
+
+			if u.UpdateId >= offset {
+				updates = append(updates, u)
+			}
+		}
+
+		notify := s.notify
+		s.mu.Unlock()
+
+		if len(updates) > 0 || timeout <= 0 {
+			return updates, nil
+		}
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			timeout = 0
+		case <-r.Context().Done():
+			return updates, nil
+		}
+	}
+}
+
+func (s *Server) sendMessage(call *Call) (interface{}, error) {
+	text, entities, err := formattedText(call.Params["text"], call.Params["parse_mode"], call.Params["entities"])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if text == "" {
+		return nil, BadRequest("message text is empty")
+	}
+
+	if tgbot.Utf16Len(text) > tgbot.MaxMessageLength {
+		return nil, BadRequest("message is too long")
+	}
+
+	msg, err := s.newMessage(call)
+
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Text = text
+
+	if len(entities) > 0 {
+		msg.Entities = &entities
+	}
+
+	return s.send(msg), nil
+}
+
+func (s *Server) sendPhoto(call *Call) (interface{}, error) {
+	msg, err := s.newMessage(call)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.setCaption(msg, call); err != nil {
+		return nil, err
+	}
+
+	f, err := s.inputFile(call, "photo")
+
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Photo = &[]tgbot.PhotoSize{{
+		FileId:       f.FileId,
+		FileUniqueId: f.FileUniqueId,
+		Width:        800,
+		Height:       600,
+		FileSize:     f.FileSize,
+	}}
+
+	return s.send(msg), nil
+}
+
+func (s *Server) sendSticker(call *Call) (interface{}, error) {
+	msg, err := s.newMessage(call)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := s.inputFile(call, "sticker")
+
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Sticker = &tgbot.Sticker{
+		FileId:       f.FileId,
+		FileUniqueId: f.FileUniqueId,
+		FileSize:     f.FileSize,
+		Width:        512,
+		Height:       512,
+	}
+
+	return s.send(msg), nil
+}
+
+func (s *Server) getFile(call *Call) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[call.Params["file_id"]]
+
+	if !ok {
+		return nil, BadRequest("invalid file_id")
+	}
+
+	return f.file, nil
+}
+
+func (s *Server) editMessageText(call *Call) (interface{}, error) {
+	text, entities, err := formattedText(call.Params["text"], call.Params["parse_mode"], call.Params["entities"])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if text == "" {
+		return nil, BadRequest("message text is empty")
+	}
+
+	return s.edit(call, func(msg *tgbot.Message) error {
+		if msg.Text == "" {
+			return BadRequest("there is no text in the message to edit")
+		}
+
+		msg.Text = text
+		msg.Entities = nil
+
+		if len(entities) > 0 {
+			msg.Entities = &entities
+		}
+
+		return nil
+	})
+}
+
+func (s *Server) editMessageCaption(call *Call) (interface{}, error) {
+	return s.edit(call, func(msg *tgbot.Message) error {
+		if msg.Text != "" {
+			return BadRequest("there is no caption in the message to edit")
+		}
+
+		return s.setCaption(msg, call)
+	})
+}
+
+// edit applies the change to the message identified by the parameters of the call and returns
+// the edited message, or true for inline messages.
+func (s *Server) edit(call *Call, change func(msg *tgbot.Message) error) (interface{}, error) {
+	if call.Params["inline_message_id"] != "" {
+		return true, nil
+	}
+
+	chatId, err := strconv.ParseInt(call.Params["chat_id"], 10, 64)
+
+	if err != nil {
+		return nil, BadRequest("chat not found")
+	}
+
+	messageId, _ := strconv.Atoi(call.Params["message_id"])
+	markup, err := replyMarkup(call)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chat, ok := s.chats[chatId]
+
+	if !ok {
+		return nil, BadRequest("chat not found")
+	}
+
+	for _, msg := range chat.messages {
+		if msg.MessageId != messageId {
+			continue
+		}
+
+		if msg.From == nil || msg.From.Id != s.Me.Id {
+			return nil, BadRequest("message can't be edited")
+		}
+
+		edited := *msg
+
+		if err = change(&edited); err != nil {
+			return nil, err
+		}
+
+		edited.ReplyMarkup = markup
+
+		if sameContent(msg, &edited) {
+			return nil, BadRequest("message is not modified")
+		}
+
+		edited.EditDate = int(time.Now().Unix())
+		*msg = edited
+		result := edited
+
+		return &result, nil
+	}
+
+	return nil, BadRequest("message to edit not found")
+}
+
+func sameContent(a, b *tgbot.Message) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+
+	return string(ja) == string(jb)
+}
+
+// newMessage returns a message from the bot to the chat of the call, not yet added to the chat.
+func (s *Server) newMessage(call *Call) (*tgbot.Message, error) {
+	chatId, err := strconv.ParseInt(call.Params["chat_id"], 10, 64)
+
+	if err != nil {
+		return nil, BadRequest("chat not found")
+	}
+
+	markup, err := replyMarkup(call)
+
+	if err != nil {
+		return nil, err
+	}
+
+	me := s.Me
+	msg := &tgbot.Message{
+		From:        &me,
+		Date:        int(time.Now().Unix()),
+		ReplyMarkup: markup,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chat := s.chat(chatId)
+	chatCopy := chat.chat
+	msg.Chat = &chatCopy
+
+	if replyTo, _ := strconv.Atoi(call.Params["reply_to_message_id"]); replyTo != 0 {
+		for _, m := range chat.messages {
+			if m.MessageId == replyTo {
+				reply := *m
+				reply.ReplyToMessage = nil
+				msg.ReplyToMessage = &reply
+			}
+		}
+
+		if msg.ReplyToMessage == nil {
+			return nil, BadRequest("reply message not found")
+		}
+	}
+
+	return msg, nil
+}
+
+// send adds the message to its chat and records it as sent.
+func (s *Server) send(msg *tgbot.Message) *tgbot.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.storeMessage(msg)
+	sent := *msg
+	s.sent = append(s.sent, &sent)
+	close(s.sentNotify)
+	s.sentNotify = make(chan struct{})
+	result := *msg
+
+	return &result
+}
+
+// storeMessage adds the message to its chat, assigning the next identifier if MessageId is zero.
+// s.mu must be held.
+func (s *Server) storeMessage(msg *tgbot.Message) {
+	chat := s.chat(msg.Chat.Id)
+
+	if msg.MessageId == 0 {
+		msg.MessageId = chat.nextMessageId
+	}
+
+	if msg.MessageId >= chat.nextMessageId {
+		chat.nextMessageId = msg.MessageId + 1
+	}
+
+	chat.messages = append(chat.messages, msg)
+}
+
+// chat returns the state of the chat, creating a private chat for positive identifiers and a supergroup
+// for negative ones. s.mu must be held.
+func (s *Server) chat(id int64) *chatState {
+	chat, ok := s.chats[id]
+
+	if !ok {
+		chat = &chatState{chat: tgbot.Chat{Id: id, Type: "private"}, nextMessageId: 1}
+
+		if id < 0 {
+			chat.chat.Type = "supergroup"
+		}
+
+		s.chats[id] = chat
+	}
+
+	return chat
+}
+
+func (s *Server) setCaption(msg *tgbot.Message, call *Call) error {
+	caption, entities, err := formattedText(call.Params["caption"], call.Params["parse_mode"], call.Params["caption_entities"])
+
+	if err != nil {
+		return err
+	}
+
+	if tgbot.Utf16Len(caption) > tgbot.MaxCaptionLength {
+		return BadRequest("message caption is too long")
+	}
+
+	msg.Caption = caption
+	msg.CaptionEntities = nil
+
+	if len(entities) > 0 {
+		msg.CaptionEntities = &entities
+	}
+
+	return nil
+}
+
+// inputFile returns the file passed as the parameter, which is either uploaded, the identifier
+// of a known file or a URL.
+func (s *Server) inputFile(call *Call, name string) (tgbot.File, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if upload, ok := call.Uploads[name]; ok {
+		return s.storeFile(upload.Data, name+"s/"+upload.Name), nil
+	}
+
+	value := call.Params[name]
+
+	if value == "" {
+		return tgbot.File{}, BadRequest("there is no %s in the request", name)
+	}
+
+	if f, ok := s.files[value]; ok {
+		return f.file, nil
+	}
+
+	return s.storeFile(nil, ""), nil
+}
+
+// storeFile stores the file and returns its description. s.mu must be held.
+func (s *Server) storeFile(data []byte, path string) tgbot.File {
+	s.nextFileId++
+	id := strconv.Itoa(s.nextFileId)
+	f := tgbot.File{
+		FileId:       "file-" + id,
+		FileUniqueId: "unique-" + id,
+		FileSize:     len(data),
+	}
+
+	if path != "" {
+		f.FilePath = id + "/" + path
+	}
+
+	s.files[f.FileId] = &storedFile{file: f, data: data}
+
+	return f
+}
+
+// formattedText returns the plain text and the entities of text formatted with the parse mode
+// or the JSON-serialized entities. The legacy Markdown parse mode is rejected.
+func formattedText(text, parseMode, entitiesJson string) (string, []tgbot.MessageEntity, error) {
+	var entities []tgbot.MessageEntity
+	var err error
+
+	switch parseMode {
+	case tgbot.ParseModeHTML:
+		text, entities, err = tgbot.ParseHTML(text)
+	case tgbot.ParseModeMarkdownV2:
+		text, entities, err = tgbot.ParseMarkdownV2(text)
+	case "":
+		if entitiesJson != "" {
+			err = json.Unmarshal([]byte(entitiesJson), &entities)
+		}
+	case "Markdown":
+		// The legacy mode is not parsed: passing its text on as is would hide the markup bugs
+		// a test is meant to catch.
+		return "", nil, BadRequest("legacy Markdown parse_mode is not supported, use MarkdownV2 or HTML")
+	default:
+		return "", nil, BadRequest("unsupported parse_mode")
+	}
+
+	if err != nil {
+		return "", nil, BadRequest("can't parse entities: %v", err)
+	}
+
+	return text, entities, nil
+}
+
+func replyMarkup(call *Call) (*tgbot.InlineKeyboardMarkup, error) {
+	data := call.Params["reply_markup"]
+
+	if data == "" {
+		return nil, nil
+	}
+
+	var markup tgbot.InlineKeyboardMarkup
+
+	if err := json.Unmarshal([]byte(data), &markup); err != nil {
+		return nil, BadRequest("can't parse reply keyboard markup JSON object")
+	}
+
+	if markup.InlineKeyboard == nil {
+		// Reply keyboards are not attached to messages.
+		return nil, nil
+	}
+
+	return &markup, nil
+}
@@ -0,0 +1,395 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbottest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// RecordMode selects whether a Recorder records or replays requests.
+type RecordMode int
+
+const (
+	// Replay answers requests with the responses read from the fixture file, without network access.
+	Replay RecordMode = iota
+	// Record sends requests to the Bot API and records the requests and the responses.
+	Record
+)
+
+// redactedToken replaces the bot token in recorded requests and responses.
+const redactedToken = "<token>"
+
+// Interaction is a recorded request to the Bot API and its response.
+type Interaction struct {
+	// Method is the Bot API method, or “file/” followed by the file path for file downloads.
+	Method string `json:"method"`
+	// Params are the normalized parameters of the request. Uploaded files are represented by their name
+	// and the SHA-256 hash of their content.
+	Params map[string]string `json:"params,omitempty"`
+	Status int               `json:"status"`
+	// Response is the body of a JSON response.
+	Response json.RawMessage `json:"response,omitempty"`
+	// Body is the body of other responses, such as downloaded files.
+	Body []byte `json:"body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that records Bot API requests and responses into a fixture file,
+// and replays them later, so that handlers can be tested against real traffic offline:
+//
+//	rec, err := tgbottest.NewRecorder("testdata/start.json", tgbottest.Replay)
+//	bot := &tgbot.Bot{Token: token, Client: rec.Client()}
+//
+// The bot token is replaced with a placeholder in the fixture. When replaying, a request is answered
+// with the first recorded interaction not yet replayed with the same method and parameters.
+type Recorder struct {
+	// Transport sends the requests in Record mode, http.DefaultTransport if nil.
+	Transport http.RoundTripper
+	// IgnoreParams lists parameters that are not compared when replaying, such as a getUpdates timeout.
+	IgnoreParams []string
+
+	path         string
+	mode         RecordMode
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+}
+
+// NewRecorder returns a recorder for the fixture file at path. In Replay mode, the file is read;
+// in Record mode, it is written by Save.
+func NewRecorder(path string, mode RecordMode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode}
+
+	if mode == Record {
+		return r, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &r.interactions); err != nil {
+		return nil, fmt.Errorf("tgbottest: invalid fixture %s: %v", path, err)
+	}
+
+	r.replayed = make([]bool, len(r.interactions))
+
+	return r, nil
+}
+
+// Client returns an HTTP client that uses the recorder, to be set as Bot.Client.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Save writes the recorded interactions to the fixture file. It does nothing in Replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != Record {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	r.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(r.path, append(data, '\n'), 0644)
+}
+
+// Unreplayed returns the recorded interactions that were not replayed, e.g. to check that a handler
+// made all the requests it made when the fixture was recorded.
+func (r *Recorder) Unreplayed() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var left []Interaction
+
+	for i, done := range r.replayed {
+		if !done {
+			left = append(left, r.interactions[i])
+		}
+	}
+
+	return left
+}
+
+// RoundTrip records or replays the request.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	method, token := splitBotPath(req.URL.Path)
+
+	if method == "" {
+		return nil, fmt.Errorf("tgbottest: %s is not a Bot API URL", req.URL.Path)
+	}
+
+	params, err := requestParams(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if token != "" {
+		for name, value := range params {
+			params[name] = strings.Replace(value, token, redactedToken, -1)
+		}
+	}
+
+	if r.mode == Replay {
+		return r.replay(req, method, params)
+	}
+
+	transport := r.Transport
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	in := Interaction{Method: method, Params: params, Status: resp.StatusCode}
+
+	if token != "" {
+		body = bytes.Replace(body, []byte(token), []byte(redactedToken), -1)
+	}
+
+	if json.Valid(body) {
+		in.Response = body
+	} else {
+		in.Body = body
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, in)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, method string, params map[string]string) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, in := range r.interactions {
+		if r.replayed[i] || in.Method != method || !r.sameParams(in.Params, params) {
+			continue
+		}
+
+		r.replayed[i] = true
+		body := []byte(in.Response)
+		header := http.Header{"Content-Type": {"application/json"}}
+
+		if in.Response == nil {
+			body = in.Body
+			header.Set("Content-Type", "application/octet-stream")
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+			StatusCode:    in.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("tgbottest: no recorded response for %s with %v", method, params)
+}
+
+func (r *Recorder) sameParams(a, b map[string]string) bool {
+	if len(r.IgnoreParams) == 0 {
+		return reflect.DeepEqual(nonNil(a), nonNil(b))
+	}
+
+	strip := func(params map[string]string) map[string]string {
+		stripped := make(map[string]string, len(params))
+
+		for name, value := range params {
+			if !contains(r.IgnoreParams, name) {
+				stripped[name] = value
+			}
+		}
+
+		return stripped
+	}
+
+	return reflect.DeepEqual(strip(a), strip(b))
+}
+
+func nonNil(params map[string]string) map[string]string {
+	if params == nil {
+		return map[string]string{}
+	}
+
+	return params
+}
+
+// splitBotPath returns the method and the token of a Bot API URL path, or of a file URL path
+// with the method “file/” followed by the file path.
+func splitBotPath(path string) (method, token string) {
+	prefix := "/bot"
+
+	if strings.HasPrefix(path, "/file/bot") {
+		prefix = "/file/bot"
+	}
+
+	rest := strings.TrimPrefix(path, prefix)
+
+	if rest == path {
+		return "", ""
+	}
+
+	i := strings.IndexByte(rest, '/')
+
+	if i < 0 {
+		return "", ""
+	}
+
+	token, method = rest[:i], rest[i+1:]
+
+	if prefix == "/file/bot" {
+		method = "file/" + method
+	}
+
+	return method, token
+}
+
+// requestParams returns the normalized parameters of a request, leaving its body intact.
+// Values that are JSON objects or arrays are re-encoded with sorted keys.
+func requestParams(req *http.Request) (map[string]string, error) {
+	params := make(map[string]string)
+
+	for name, values := range req.URL.Query() {
+		params[name] = values[0]
+	}
+
+	if req.Body == nil {
+		return params, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	mediaType, mediaParams, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "application/json":
+		var fields map[string]json.RawMessage
+
+		if err = json.Unmarshal(body, &fields); err != nil {
+			return nil, err
+		}
+
+		for name, raw := range fields {
+			var value string
+
+			if json.Unmarshal(raw, &value) != nil {
+				value = string(raw)
+			}
+
+			params[name] = value
+		}
+	case "multipart/form-data":
+		mr := multipart.NewReader(bytes.NewReader(body), mediaParams["boundary"])
+
+		for {
+			part, err := mr.NextPart()
+
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			data, err := ioutil.ReadAll(part)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if part.FileName() != "" {
+				sum := sha256.Sum256(data)
+				params[part.FormName()] = part.FileName() + " sha256:" + hex.EncodeToString(sum[:])
+			} else {
+				params[part.FormName()] = string(data)
+			}
+		}
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for name, values := range form {
+			params[name] = values[0]
+		}
+	}
+
+	for name, value := range params {
+		params[name] = normalizeJson(value)
+	}
+
+	return params, nil
+}
+
+func normalizeJson(value string) string {
+	if !strings.HasPrefix(value, "{") && !strings.HasPrefix(value, "[") {
+		return value
+	}
+
+	var v interface{}
+
+	if json.Unmarshal([]byte(value), &v) != nil {
+		return value
+	}
+
+	data, err := json.Marshal(v)
+
+	if err != nil {
+		return value
+	}
+
+	return string(data)
+}
@@ -0,0 +1,195 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbottest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+	"github.com/modern-dev/tgbot-go/tgbottest"
+)
+
+// recordFixture records a conversation with a fake server into a fixture file and returns its path.
+func recordFixture(t *testing.T) string {
+	srv, bot := newServer(t)
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	rec, err := tgbottest.NewRecorder(path, tgbottest.Record)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bot.Client = rec.Client()
+	srv.UserMessage(tgbot.Chat{Id: 42, Type: "private"}, tgbot.User{Id: 7}, "/start")
+	upload := filepath.Join(t.TempDir(), "cat.jpg")
+
+	if err = ioutil.WriteFile(upload, []byte("meow"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = bot.GetUpdates(&tgbot.GetUpdatesOptions{Timeout: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = bot.SendMessage("42", "hi "+bot.Token, &tgbot.SendMessageOptions{
+		Entities: []tgbot.MessageEntity{{Type: tgbot.EntityBold, Offset: 0, Length: 2}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := bot.SendPhoto("42", tgbot.InputFileFromDisk(upload), nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := bot.GetFile((*msg.Photo)[0].FileId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = bot.DownloadFile(f.FilePath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// replayBot returns a bot replaying the fixture. Its server is closed, so every request must be replayed.
+func replayBot(t *testing.T, rec *tgbottest.Recorder) *tgbot.Bot {
+	srv := tgbottest.NewServer()
+	srv.Close()
+	bot := srv.NewBot()
+	bot.Client = rec.Client()
+
+	return bot
+}
+
+func TestRecorderRedactsToken(t *testing.T) {
+	data, err := ioutil.ReadFile(recordFixture(t))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(data, []byte(tgbottest.Token)) {
+		t.Errorf("fixture contains the token:\n%s", data)
+	}
+
+	var interactions []tgbottest.Interaction
+
+	if err = json.Unmarshal(data, &interactions); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(interactions) != 5 || interactions[1].Params["text"] != "hi <token>" {
+		t.Errorf("fixture lacks the requests:\n%s", data)
+	}
+}
+
+func TestRecorderReplaysFixture(t *testing.T) {
+	rec, err := tgbottest.NewRecorder(recordFixture(t), tgbottest.Replay)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec.IgnoreParams = []string{"timeout"}
+	bot := replayBot(t, rec)
+	upload := filepath.Join(t.TempDir(), "cat.jpg")
+
+	if err = ioutil.WriteFile(upload, []byte("meow"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	updates, err := bot.GetUpdates(&tgbot.GetUpdatesOptions{Timeout: 30})
+
+	if err != nil || len(updates) != 1 || updates[0].Message.Text != "/start" {
+		t.Fatalf("replayed updates = %+v, %v", updates, err)
+	}
+
+	// The entities are sent as JSON with the same content but another formatting.
+	msg, err := bot.SendMessage("42", "hi "+bot.Token, &tgbot.SendMessageOptions{
+		Entities: []tgbot.MessageEntity{{Type: tgbot.EntityBold, Offset: 0, Length: 2}},
+	})
+
+	if err != nil || !strings.HasPrefix(msg.Text, "hi ") {
+		t.Fatalf("replayed message = %+v, %v", msg, err)
+	}
+
+	// Uploads are matched by their name and content, not by their path.
+	if msg, err = bot.SendPhoto("42", tgbot.InputFileFromDisk(upload), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := bot.GetFile((*msg.Photo)[0].FileId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := bot.DownloadFile(f.FilePath); err != nil || string(data) != "meow" {
+		t.Errorf("replayed download = %q, %v", data, err)
+	}
+
+	if left := rec.Unreplayed(); len(left) != 0 {
+		t.Errorf("unreplayed interactions %+v", left)
+	}
+
+	// Each interaction is replayed once.
+	if _, err = bot.GetUpdates(&tgbot.GetUpdatesOptions{Timeout: 30}); err == nil {
+		t.Error("interaction replayed twice")
+	}
+}
+
+func TestRecorderRejectsUnrecordedRequests(t *testing.T) {
+	rec, err := tgbottest.NewRecorder(recordFixture(t), tgbottest.Replay)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bot := replayBot(t, rec)
+
+	if _, err = bot.SendMessage("42", "bye", nil); err == nil {
+		t.Error("request with other parameters was replayed")
+	}
+
+	// The timeout is compared unless it is ignored.
+	if _, err = bot.GetUpdates(&tgbot.GetUpdatesOptions{Timeout: 30}); err == nil {
+		t.Error("request with another timeout was replayed")
+	}
+
+	if left := rec.Unreplayed(); len(left) != 5 {
+		t.Errorf("%d interactions left, want 5", len(left))
+	}
+}
+
+func TestNewRecorderRejectsInvalidFixtures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.json")
+
+	if _, err := tgbottest.NewRecorder(path, tgbottest.Replay); err == nil {
+		t.Error("missing fixture was read")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tgbottest.NewRecorder(path, tgbottest.Replay); err == nil {
+		t.Error("invalid fixture was read")
+	}
+}
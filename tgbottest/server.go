@@ -0,0 +1,477 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+// Package tgbottest provides a fake Bot API server, a record and replay transport, and a harness
+// for testing bots without reaching Telegram.
+package tgbottest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+)
+
+// Token is the token of the bot served by a Server made by NewServer.
+const Token = "123456:TEST-TOKEN"
+
+// Error is an error returned by a Bot API method, with the HTTP status code and the description
+// of the error response.
+type Error struct {
+	Code        int
+	Description string
+}
+
+func (e *Error) Error() string {
+	return e.Description
+}
+
+// BadRequest returns the error Telegram returns for invalid parameters.
+func BadRequest(format string, args ...interface{}) *Error {
+	return &Error{Code: http.StatusBadRequest, Description: "Bad Request: " + fmt.Sprintf(format, args...)}
+}
+
+// Upload is a file uploaded with multipart/form-data.
+type Upload struct {
+	Name string
+	Data []byte
+}
+
+// Call is a request received by the server.
+type Call struct {
+	Method  string
+	Params  map[string]string
+	Uploads map[string]Upload
+}
+
+// MethodHandler implements a Bot API method. The result is marshalled into the response;
+// an *Error is returned as the error response.
+type MethodHandler func(call *Call) (interface{}, error)
+
+// Server is an in-process fake Bot API server. It keeps the chats and the files of a single bot
+// in memory, queues updates injected by tests for getUpdates, and records the requests it receives.
+//
+// It implements getMe, getUpdates, sendMessage, sendPhoto, sendSticker, getFile, editMessageText,
+// editMessageCaption and answerCallbackQuery; other methods can be added with Handle. Chats are
+// created when a message is sent to an unknown chat identifier. Text formatted with HTML or MarkdownV2
+// is parsed into entities; the legacy Markdown parse mode is refused with a Bad Request error.
+type Server struct {
+	// Me is the user of the bot.
+	Me tgbot.User
+
+	srv          *httptest.Server
+	mu           sync.Mutex
+	methods      map[string]MethodHandler
+	updates      []tgbot.Update
+	nextUpdateId int
+	notify       chan struct{}
+	chats        map[int64]*chatState
+	files        map[string]*storedFile
+	nextFileId   int
+	calls        []Call
+	sent         []*tgbot.Message
+	sentNotify   chan struct{}
+}
+
+type chatState struct {
+	chat          tgbot.Chat
+	messages      []*tgbot.Message
+	nextMessageId int
+}
+
+type storedFile struct {
+	file tgbot.File
+	data []byte
+}
+
+// NewServer starts a server for the bot with Token. Close it when the test is done.
+func NewServer() *Server {
+	s := &Server{
+		Me: tgbot.User{
+			Id:        123456,
+			IsBot:     true,
+			FirstName: "Test Bot",
+			Username:  "test_bot",
+		},
+		methods:      make(map[string]MethodHandler),
+		nextUpdateId: 1,
+		notify:       make(chan struct{}),
+		chats:        make(map[int64]*chatState),
+		files:        make(map[string]*storedFile),
+		sentNotify:   make(chan struct{}),
+	}
+
+	s.srv = httptest.NewServer(s)
+
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// URL returns the base URL of the server.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// ApiEndpoint returns the endpoint to be set as Bot.ApiEndpoint.
+func (s *Server) ApiEndpoint() string {
+	return s.srv.URL + "/bot%s/%s"
+}
+
+// FileEndpoint returns the endpoint to be set as Bot.FileEndpoint.
+func (s *Server) FileEndpoint() string {
+	return s.srv.URL + "/file/bot%s/%s"
+}
+
+// NewBot returns a bot that uses the server.
+func (s *Server) NewBot() *tgbot.Bot {
+	me := s.Me
+
+	return &tgbot.Bot{
+		Token:        Token,
+		Me:           &me,
+		ApiEndpoint:  s.ApiEndpoint(),
+		FileEndpoint: s.FileEndpoint(),
+	}
+}
+
+// Handle sets the handler of the method, replacing the built-in implementation if there is one.
+func (s *Server) Handle(method string, h MethodHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.methods[method] = h
+}
+
+// AddUpdate queues the update for getUpdates and returns it. If UpdateId is zero, the next identifier
+// is assigned. Messages of the update are added to their chats.
+func (s *Server) AddUpdate(u tgbot.Update) tgbot.Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.receive(&u)
+	s.updates = append(s.updates, u)
+	close(s.notify)
+	s.notify = make(chan struct{})
+
+	return u
+}
+
+// receive assigns the next identifier to the update if it has none and adds its messages to their chats.
+// s.mu must be held.
+func (s *Server) receive(u *tgbot.Update) {
+	if u.UpdateId == 0 {
+		u.UpdateId = s.nextUpdateId
+	}
+
+	if u.UpdateId >= s.nextUpdateId {
+		s.nextUpdateId = u.UpdateId + 1
+	}
+
+	for _, msg := range []*tgbot.Message{u.Message, u.ChannelPost} {
+		if msg != nil && msg.Chat != nil {
+			s.storeMessage(msg)
+		}
+	}
+}
+
+// UserMessage queues an update with a text message from the user in the chat and returns the message.
+// A command at the start of the text gets a bot_command entity.
+func (s *Server) UserMessage(chat tgbot.Chat, from tgbot.User, text string) *tgbot.Message {
+	msg := textMessage(chat, from, text)
+	s.AddUpdate(tgbot.Update{Message: msg})
+
+	return msg
+}
+
+func textMessage(chat tgbot.Chat, from tgbot.User, text string) *tgbot.Message {
+	msg := &tgbot.Message{
+		From: &from,
+		Date: int(time.Now().Unix()),
+		Chat: &chat,
+		Text: text,
+	}
+
+	if strings.HasPrefix(text, "/") {
+		end := strings.IndexAny(text, " \n")
+
+		if end < 0 {
+			end = len(text)
+		}
+
+		msg.Entities = &[]tgbot.MessageEntity{{Type: tgbot.EntityBotCommand, Length: tgbot.Utf16Len(text[:end])}}
+	}
+
+	return msg
+}
+
+// Calls returns the requests received by the server for the methods, or all requests without methods.
+func (s *Server) Calls(methods ...string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []Call
+
+	for _, c := range s.calls {
+		if len(methods) == 0 || contains(methods, c.Method) {
+			calls = append(calls, c)
+		}
+	}
+
+	return calls
+}
+
+// Sent returns the messages sent by the bot as they were sent, in order.
+func (s *Server) Sent() []*tgbot.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*tgbot.Message(nil), s.sent...)
+}
+
+// SentTo returns the messages sent by the bot to the chat as they were sent, in order.
+func (s *Server) SentTo(chatId int64) []*tgbot.Message {
+	var sent []*tgbot.Message
+
+	for _, msg := range s.Sent() {
+		if msg.Chat.Id == chatId {
+			sent = append(sent, msg)
+		}
+	}
+
+	return sent
+}
+
+// WaitSent waits until the bot has sent at least n messages and returns them, or returns an error
+// after the timeout.
+func (s *Server) WaitSent(n int, timeout time.Duration) ([]*tgbot.Message, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		sent := append([]*tgbot.Message(nil), s.sent...)
+		notify := s.sentNotify
+		s.mu.Unlock()
+
+		if len(sent) >= n {
+			return sent, nil
+		}
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return sent, fmt.Errorf("tgbottest: %d of %d messages sent in %v", len(sent), n, timeout)
+		}
+	}
+}
+
+// Messages returns the current messages of the chat, with the edits applied, in order.
+func (s *Server) Messages(chatId int64) []tgbot.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chat, ok := s.chats[chatId]
+
+	if !ok {
+		return nil
+	}
+
+	messages := make([]tgbot.Message, len(chat.messages))
+
+	for i, msg := range chat.messages {
+		messages[i] = *msg
+	}
+
+	return messages
+}
+
+// FileData returns the content of an uploaded file.
+func (s *Server) FileData(fileId string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[fileId]
+
+	if !ok || f.data == nil {
+		return nil, false
+	}
+
+	return f.data, true
+}
+
+// ServeHTTP serves Bot API requests and file downloads.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if path := strings.TrimPrefix(r.URL.Path, "/file/bot"+Token+"/"); path != r.URL.Path {
+		s.serveFile(w, path)
+
+		return
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, "/bot"+Token+"/")
+
+	if method == r.URL.Path {
+		writeResponse(w, nil, &Error{Code: http.StatusUnauthorized, Description: "Unauthorized"})
+
+		return
+	}
+
+	call, err := parseCall(r, method)
+
+	if err != nil {
+		writeResponse(w, nil, BadRequest("%v", err))
+
+		return
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, *call)
+	h, ok := s.methods[method]
+	s.mu.Unlock()
+
+	if !ok {
+		h, ok = s.builtin(r, method)
+	}
+
+	if !ok {
+		writeResponse(w, nil, &Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	}
+
+	result, err := h(call)
+	writeResponse(w, result, err)
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range s.files {
+		if f.file.FilePath == path && f.data != nil {
+			w.Write(f.data)
+
+			return
+		}
+	}
+
+	http.NotFound(w, nil)
+}
+
+func writeResponse(w http.ResponseWriter, result interface{}, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err != nil {
+		apiErr, ok := err.(*Error)
+
+		if !ok {
+			apiErr = &Error{Code: http.StatusInternalServerError, Description: "Internal Server Error: " + err.Error()}
+		}
+
+		w.WriteHeader(apiErr.Code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":          false,
+			"error_code":  apiErr.Code,
+			"description": apiErr.Description,
+		})
+
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":     true,
+		"result": result,
+	})
+}
+
+// parseCall reads the parameters of a request sent as JSON, multipart/form-data or a URL-encoded form.
+func parseCall(r *http.Request, method string) (*Call, error) {
+	call := &Call{
+		Method:  method,
+		Params:  make(map[string]string),
+		Uploads: make(map[string]Upload),
+	}
+
+	contentType := r.Header.Get("Content-Type")
+
+	switch {
+	case strings.HasPrefix(contentType, "application/json"):
+		body, err := ioutil.ReadAll(r.Body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var fields map[string]json.RawMessage
+
+		if err = json.Unmarshal(body, &fields); err != nil {
+			return nil, err
+		}
+
+		for name, raw := range fields {
+			var value string
+
+			if json.Unmarshal(raw, &value) != nil {
+				value = string(raw)
+			}
+
+			call.Params[name] = value
+		}
+	case strings.HasPrefix(contentType, "multipart/form-data"):
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return nil, err
+		}
+
+		for name, values := range r.MultipartForm.Value {
+			call.Params[name] = values[0]
+		}
+
+		for name, headers := range r.MultipartForm.File {
+			f, err := headers[0].Open()
+
+			if err != nil {
+				return nil, err
+			}
+
+			data, err := ioutil.ReadAll(f)
+			f.Close()
+
+			if err != nil {
+				return nil, err
+			}
+
+			call.Uploads[name] = Upload{Name: headers[0].Filename, Data: data}
+		}
+	default:
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+
+		for name, values := range r.Form {
+			call.Params[name] = values[0]
+		}
+	}
+
+	return call, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
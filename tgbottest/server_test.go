@@ -0,0 +1,248 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbottest_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tgbot "github.com/modern-dev/tgbot-go"
+	"github.com/modern-dev/tgbot-go/tgbottest"
+)
+
+func newServer(t *testing.T) (*tgbottest.Server, *tgbot.Bot) {
+	srv := tgbottest.NewServer()
+	t.Cleanup(srv.Close)
+
+	return srv, srv.NewBot()
+}
+
+func TestServerGetMe(t *testing.T) {
+	srv, bot := newServer(t)
+	me, err := bot.GetMe()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(*me, srv.Me) {
+		t.Errorf("GetMe = %+v, want %+v", *me, srv.Me)
+	}
+}
+
+func TestServerGetUpdatesConfirmsUpdatesBeforeOffset(t *testing.T) {
+	srv, bot := newServer(t)
+	chat := tgbot.Chat{Id: 42, Type: "private"}
+	user := tgbot.User{Id: 7, FirstName: "Ann"}
+
+	srv.UserMessage(chat, user, "/start now")
+	srv.UserMessage(chat, user, "hello")
+
+	updates, err := bot.GetUpdates(nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(updates) != 2 || updates[0].UpdateId != 1 || updates[1].UpdateId != 2 {
+		t.Fatalf("updates = %+v, want updates 1 and 2", updates)
+	}
+
+	if entities := updates[0].Message.Entities; entities == nil ||
+		!reflect.DeepEqual(*entities, []tgbot.MessageEntity{{Type: tgbot.EntityBotCommand, Length: 6}}) {
+		t.Errorf("entities of a command = %+v", entities)
+	}
+
+	if updates, err = bot.GetUpdates(&tgbot.GetUpdatesOptions{Offset: 2}); err != nil || len(updates) != 1 {
+		t.Fatalf("updates from offset 2 = %+v, %v, want update 2", updates, err)
+	}
+
+	// Update 1 was confirmed by the offset.
+	if updates, err = bot.GetUpdates(nil); err != nil || len(updates) != 1 || updates[0].UpdateId != 2 {
+		t.Errorf("updates after confirming update 1 = %+v, %v, want update 2", updates, err)
+	}
+}
+
+func TestServerSendMessageParsesFormattedText(t *testing.T) {
+	bold := []tgbot.MessageEntity{{Type: tgbot.EntityBold, Offset: 3, Length: 5}}
+	tests := []struct {
+		name string
+		text string
+		opts *tgbot.SendMessageOptions
+	}{
+		{"HTML", "hi <b>there</b>", &tgbot.SendMessageOptions{ParseMode: tgbot.ParseModeHTML}},
+		{"MarkdownV2", "hi *there*", &tgbot.SendMessageOptions{ParseMode: tgbot.ParseModeMarkdownV2}},
+		{"entities", "hi there", &tgbot.SendMessageOptions{Entities: bold}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, bot := newServer(t)
+			msg, err := bot.SendMessage("42", tt.text, tt.opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if msg.Text != "hi there" || msg.Entities == nil || !reflect.DeepEqual(*msg.Entities, bold) {
+				t.Errorf("sent %q with %+v, want %q with %+v", msg.Text, msg.Entities, "hi there", bold)
+			}
+
+			if sent := srv.SentTo(42); len(sent) != 1 || sent[0].MessageId != msg.MessageId {
+				t.Errorf("messages sent to the chat = %+v", sent)
+			}
+		})
+	}
+}
+
+func TestServerSendMessageRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		opts  *tgbot.SendMessageOptions
+		error string
+	}{
+		{"legacy Markdown", "*hi*", &tgbot.SendMessageOptions{ParseMode: "Markdown"}, "legacy Markdown"},
+		{"unknown parse mode", "hi", &tgbot.SendMessageOptions{ParseMode: "BBCode"}, "unsupported parse_mode"},
+		{"unclosed markup", "*hi", &tgbot.SendMessageOptions{ParseMode: tgbot.ParseModeMarkdownV2}, "can't parse entities"},
+		{"empty text", "<b></b>", &tgbot.SendMessageOptions{ParseMode: tgbot.ParseModeHTML}, "message text is empty"},
+		{"long text", strings.Repeat("a", tgbot.MaxMessageLength+1), nil, "message is too long"},
+		{"unknown reply", "hi", &tgbot.SendMessageOptions{ReplyToMessageId: 5}, "reply message not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, bot := newServer(t)
+			_, err := bot.SendMessage("42", tt.text, tt.opts)
+
+			if err == nil || !strings.Contains(err.Error(), tt.error) {
+				t.Errorf("SendMessage error = %v, want %q", err, tt.error)
+			}
+
+			if sent := srv.Sent(); len(sent) != 0 {
+				t.Errorf("sent %+v", sent)
+			}
+		})
+	}
+}
+
+func TestServerEditMessageText(t *testing.T) {
+	srv, bot := newServer(t)
+	msg, err := bot.SendMessage("42", "draft", nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := tgbot.ChatMessage("42", msg.MessageId)
+
+	if _, err = bot.EditMessageText("final", target, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = bot.EditMessageText("final", target, nil); err == nil || !strings.Contains(err.Error(), "not modified") {
+		t.Errorf("unchanged edit error = %v, want message is not modified", err)
+	}
+
+	if _, err = bot.EditMessageText("x", tgbot.ChatMessage("42", msg.MessageId+1), nil); err == nil {
+		t.Error("edit of an unknown message succeeded")
+	}
+
+	user := srv.UserMessage(tgbot.Chat{Id: 42, Type: "private"}, tgbot.User{Id: 7}, "mine")
+
+	if _, err = bot.EditMessageText("x", tgbot.ChatMessage("42", user.MessageId), nil); err == nil {
+		t.Error("edit of a user's message succeeded")
+	}
+
+	messages := srv.Messages(42)
+
+	if len(messages) != 2 || messages[0].Text != "final" || messages[0].EditDate == 0 {
+		t.Errorf("messages = %+v, want the edited message and the user's", messages)
+	}
+
+	// Sent keeps the message as it was sent.
+	if sent := srv.Sent(); len(sent) != 1 || sent[0].Text != "draft" {
+		t.Errorf("sent = %+v, want the draft", sent)
+	}
+}
+
+func TestServerStoresUploadedFiles(t *testing.T) {
+	srv, bot := newServer(t)
+	path := filepath.Join(t.TempDir(), "cat.jpg")
+
+	if err := ioutil.WriteFile(path, []byte("meow"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := bot.SendPhoto("42", tgbot.InputFileFromDisk(path), nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Photo == nil || len(*msg.Photo) != 1 {
+		t.Fatalf("photo = %+v", msg.Photo)
+	}
+
+	fileId := (*msg.Photo)[0].FileId
+
+	if data, ok := srv.FileData(fileId); !ok || string(data) != "meow" {
+		t.Errorf("FileData = %q, %v", data, ok)
+	}
+
+	f, err := bot.GetFile(fileId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := bot.DownloadFile(f.FilePath)
+
+	if err != nil || string(data) != "meow" {
+		t.Errorf("DownloadFile = %q, %v", data, err)
+	}
+
+	// The file is sent again by its identifier.
+	if msg, err = bot.SendPhoto("42", tgbot.InputFileFromId(fileId), nil); err != nil || (*msg.Photo)[0].FileId != fileId {
+		t.Errorf("photo sent by identifier = %+v, %v", msg, err)
+	}
+
+	if _, err = bot.GetFile("unknown"); err == nil {
+		t.Error("GetFile of an unknown file succeeded")
+	}
+}
+
+func TestServerHandle(t *testing.T) {
+	srv, bot := newServer(t)
+
+	srv.Handle("sendMessage", func(call *tgbottest.Call) (interface{}, error) {
+		return nil, tgbottest.BadRequest("chat %s is muted", call.Params["chat_id"])
+	})
+
+	if _, err := bot.SendMessage("42", "hi", nil); err == nil || !strings.Contains(err.Error(), "chat 42 is muted") {
+		t.Errorf("SendMessage error = %v, want the error of the handler", err)
+	}
+
+	if _, err := bot.GetStickerSet("animals"); err == nil {
+		t.Error("unknown method succeeded")
+	}
+
+	if calls := srv.Calls("sendMessage", "getStickerSet"); len(calls) != 2 || calls[1].Params["name"] != "animals" {
+		t.Errorf("calls = %+v", calls)
+	}
+}
+
+func TestServerRejectsOtherTokens(t *testing.T) {
+	_, bot := newServer(t)
+	bot.Token = "654321:OTHER"
+
+	if _, err := bot.GetMe(); err == nil {
+		t.Error("request with another token succeeded")
+	}
+}
@@ -0,0 +1,134 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// apiCall is a Bot API request received by a stubApi.
+type apiCall struct {
+	method string
+	params map[string]string
+	// deadline is the deadline of the context of the request, zero if it has none.
+	deadline time.Time
+}
+
+// apiHandler returns the result of a Bot API request, or an error to fail the request.
+type apiHandler func(ctx context.Context, method string, params map[string]string) (interface{}, error)
+
+// stubApi answers the Bot API requests made with http.DefaultClient while a test runs.
+type stubApi struct {
+	// handle answers the requests with true if nil.
+	handle apiHandler
+
+	mu    sync.Mutex
+	calls []apiCall
+}
+
+func newStubApi(t *testing.T, handle apiHandler) *stubApi {
+	s := &stubApi{handle: handle}
+	transport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = s
+	t.Cleanup(func() { http.DefaultClient.Transport = transport })
+
+	return s
+}
+
+func (s *stubApi) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	params := map[string]string{}
+
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/") {
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			return nil, err
+		}
+
+		for name, values := range req.MultipartForm.Value {
+			params[name] = values[0]
+		}
+	} else {
+		var values map[string]interface{}
+
+		if err := json.NewDecoder(req.Body).Decode(&values); err != nil {
+			return nil, err
+		}
+
+		for name, value := range values {
+			if str, ok := value.(string); ok {
+				params[name] = str
+			} else {
+				data, _ := json.Marshal(value)
+				params[name] = string(data)
+			}
+		}
+	}
+
+	call := apiCall{method: method, params: params}
+	call.deadline, _ = req.Context().Deadline()
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	s.mu.Unlock()
+
+	var result interface{} = true
+
+	if s.handle != nil {
+		var err error
+
+		if result, err = s.handle(req.Context(), method, params); err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"ok": true, "result": result})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// Calls returns the requests for the methods received so far, or all requests if no method is given.
+func (s *stubApi) Calls(methods ...string) []apiCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []apiCall
+
+	for _, call := range s.calls {
+		if len(methods) == 0 {
+			calls = append(calls, call)
+
+			continue
+		}
+
+		for _, method := range methods {
+			if call.method == method {
+				calls = append(calls, call)
+
+				break
+			}
+		}
+	}
+
+	return calls
+}
@@ -5,6 +5,41 @@
 
 package tgbot
 
+import "encoding/json"
+
+// Update represents an incoming update. At most one of the optional parameters can be present in any given update.
+type Update struct {
+	// The update‘s unique identifier. Update identifiers start from a certain positive number and increase
+	// sequentially.
+	UpdateId int `json:"update_id"`
+	// Optional. New incoming message of any kind — text, photo, sticker, etc.
+	Message *Message `json:"message,omitempty"`
+	// Optional. New version of a message that is known to the bot and was edited
+	EditedMessage *Message `json:"edited_message,omitempty"`
+	// Optional. New incoming channel post of any kind — text, photo, sticker, etc.
+	ChannelPost *Message `json:"channel_post,omitempty"`
+	// Optional. New version of a channel post that is known to the bot and was edited
+	EditedChannelPost *Message `json:"edited_channel_post,omitempty"`
+	// Optional. New incoming inline query
+	InlineQuery *InlineQuery `json:"inline_query,omitempty"`
+	// Optional. The result of an inline query that was chosen by a user and sent to their chat partner.
+	ChosenInlineResult *ChosenInlineResult `json:"chosen_inline_result,omitempty"`
+	// Optional. New incoming callback query
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+	// Optional. New incoming shipping query. Only for invoices with flexible price
+	ShippingQuery *ShippingQuery `json:"shipping_query,omitempty"`
+	// Optional. New incoming pre-checkout query. Contains full information about checkout
+	PreCheckoutQuery *PreCheckoutQuery `json:"pre_checkout_query,omitempty"`
+	// Optional. New poll state. Bots receive only updates about stopped polls and polls, which are sent by the bot
+	Poll *Poll `json:"poll,omitempty"`
+	// Optional. A user changed their answer in a non-anonymous poll. Bots receive new votes only in polls
+	// that were sent by the bot itself.
+	PollAnswer *PollAnswer `json:"poll_answer,omitempty"`
+	// Album is not part of the Bot API. It is set on updates made by a MediaGroupCollector
+	// and holds all messages of a media group in order.
+	Album []*Message `json:"-"`
+}
+
 // User object represents a Telegram user or bot.
 type User struct {
 	// Unique identifier for this user or bot
@@ -85,7 +120,7 @@ type Message struct {
 	// Optional. Message is a voice message, information about the file
 	Voice *Voice `json:"voice"`
 	// Optional. Message is a video note, information about the video message
-	VideoNoe *VideoNote `json:"video_noe"`
+	VideoNote *VideoNote `json:"video_note"`
 	// Optional. Caption for the animation, audio, document, photo, video or voice, 0-1024 characters
 	Caption string `json:"caption"`
 	// Optional. Message is a shared contact, information about the contact
@@ -137,6 +172,10 @@ type Message struct {
 	SuccessfulPayment *SuccessfulPayment `json:"successful_payment"`
 	// Optional. The domain name of the website on which the user has logged in.
 	ConnectedWebsite string `json:"connected_website"`
+	// Optional. Telegram Passport data
+	PassportData *PassportData `json:"passport_data,omitempty"`
+	// Optional. Inline keyboard attached to the message. login_url buttons are represented as ordinary url buttons.
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
 }
 
 // ChatPermissions describes actions that a non-administrator user is allowed to take in a chat.
@@ -357,7 +396,7 @@ type Game struct {
 	// Title of the game
 	Title string `json:"title"`
 	// Description of the game
-	Description int `json:"description"`
+	Description string `json:"description"`
 	// Photo that will be displayed in the game message in chats.
 	Photo *[]PhotoSize `json:"photo"`
 	// Optional. Brief description of the game or high scores included in the game message.
@@ -370,6 +409,19 @@ type Game struct {
 	Animation *Animation `json:"animation,omitempty"`
 }
 
+// GameHighScore represents one row of the high scores table for a game.
+type GameHighScore struct {
+	// Position in high score table for the game
+	Position int `json:"position"`
+	// User
+	User *User `json:"user"`
+	// Score
+	Score int `json:"score"`
+}
+
+// CallbackGame is a placeholder, currently holds no information. Use BotFather to set up your game.
+type CallbackGame struct{}
+
 // VideoNote represents a video message (available in Telegram apps as of v.4.0).
 type VideoNote struct {
 	// Identifier for this file, which can be used to download or reuse the file.
@@ -449,7 +501,7 @@ type Poll struct {
 	// Poll question, 1-255 characters
 	Question string `json:"question"`
 	// List of poll options
-	Options *[]PollAnswer `json:"options"`
+	Options []PollOption `json:"options"`
 	// Total number of users that voted in the poll
 	TotalVoterCount int `json:"total_voter_count"`
 	// True, if the poll is closed
@@ -500,11 +552,42 @@ type Sticker struct {
 	// Optional. Emoji associated with the sticker
 	Emoji string `json:"emoji,omitempty"`
 	// Optional. Name of the sticker set to which the sticker belongs
-	SetMame string `json:"set_mame,omitempty"`
+	SetName string `json:"set_name,omitempty"`
 	// Optional. For mask stickers, the position where the mask should be placed
 	MaskPosition *MaskPosition `json:"mask_position,omitempty"`
 }
 
+// StickerSet represents a sticker set.
+type StickerSet struct {
+	// Sticker set name
+	Name string `json:"name"`
+	// Sticker set title
+	Title string `json:"title"`
+	// True, if the sticker set contains animated stickers
+	IsAnimated bool `json:"is_animated"`
+	// True, if the sticker set contains masks
+	ContainsMasks bool `json:"contains_masks"`
+	// List of all set stickers
+	Stickers []Sticker `json:"stickers"`
+	// Optional. Sticker set thumbnail in the .WEBP or .TGS format
+	Thumb *PhotoSize `json:"thumb,omitempty"`
+}
+
+// File represents a file ready to be downloaded. The file can be downloaded via the link
+// https://api.telegram.org/file/bot<token>/<file_path>. It is guaranteed that the link will be valid
+// for at least 1 hour.
+type File struct {
+	// Identifier for this file, which can be used to download or reuse the file.
+	FileId string `json:"file_id"`
+	// Unique identifier for this file, which is supposed to be the same over time and for different bots.
+	// Can't be used to download or reuse the file.
+	FileUniqueId string `json:"file_unique_id"`
+	// Optional. File size, if known
+	FileSize int `json:"file_size,omitempty"`
+	// Optional. File path. Use https://api.telegram.org/file/bot<token>/<file_path> to get the file.
+	FilePath string `json:"file_path,omitempty"`
+}
+
 // Invoice contains basic information about an invoice.
 type Invoice struct {
 	// Product name
@@ -566,3 +649,216 @@ type SuccessfulPayment struct {
 	// Provider payment identifier
 	ProviderPaymentChargeId string `json:"provider_payment_charge_id"`
 }
+
+// LabeledPrice represents a portion of the price for goods or services.
+type LabeledPrice struct {
+	// Portion label
+	Label string `json:"label"`
+	// Price of the product in the smallest units of the currency (integer, not float/double).
+	// For example, for a price of US$ 1.45 pass amount = 145. See the exp parameter in currencies.json,
+	// it shows the number of digits past the decimal point for each currency (2 for the majority of currencies).
+	Amount int `json:"amount"`
+}
+
+// ShippingOption represents one shipping option.
+type ShippingOption struct {
+	// Shipping option identifier
+	Id string `json:"id"`
+	// Option title
+	Title string `json:"title"`
+	// List of price portions
+	Prices []LabeledPrice `json:"prices"`
+}
+
+// ShippingQuery contains information about an incoming shipping query.
+type ShippingQuery struct {
+	// Unique query identifier
+	Id string `json:"id"`
+	// User who sent the query
+	From *User `json:"from"`
+	// Bot specified invoice payload
+	InvoicePayload string `json:"invoice_payload"`
+	// User specified shipping address
+	ShippingAddress *ShippingAddress `json:"shipping_address"`
+}
+
+// PreCheckoutQuery contains information about an incoming pre-checkout query.
+type PreCheckoutQuery struct {
+	// Unique query identifier
+	Id string `json:"id"`
+	// User who sent the query
+	From *User `json:"from"`
+	// Three-letter ISO 4217 currency code
+	Currency string `json:"currency"`
+	// Total price in the smallest units of the currency (integer, not float/double).
+	// For example, for a price of US$ 1.45 pass amount = 145. See the exp parameter in currencies.json,
+	// it shows the number of digits past the decimal point for each currency (2 for the majority of currencies).
+	TotalAmount int `json:"total_amount"`
+	// Bot specified invoice payload
+	InvoicePayload string `json:"invoice_payload"`
+	// Optional. Identifier of the shipping option chosen by the user
+	ShippingOptionId string `json:"shipping_option_id,omitempty"`
+	// Optional. Order info provided by the user
+	OrderInfo *OrderInfo `json:"order_info,omitempty"`
+}
+
+// InlineKeyboardMarkup represents an inline keyboard that appears right next to the message it belongs to.
+type InlineKeyboardMarkup struct {
+	// Array of button rows, each represented by an Array of InlineKeyboardButton objects
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// String returns the JSON-serialized keyboard to be passed as ReplyMarkup.
+func (m *InlineKeyboardMarkup) String() string {
+	b, _ := json.Marshal(m)
+
+	return string(b)
+}
+
+// InlineKeyboardButton represents one button of an inline keyboard. You must use exactly one of the optional fields.
+type InlineKeyboardButton struct {
+	// Label text on the button
+	Text string `json:"text"`
+	// Optional. HTTP or tg:// url to be opened when button is pressed
+	Url string `json:"url,omitempty"`
+	// Optional. Data to be sent in a callback query to the bot when button is pressed, 1-64 bytes
+	CallbackData string `json:"callback_data,omitempty"`
+	// Optional. If set, pressing the button will prompt the user to select one of their chats,
+	// open that chat and insert the bot‘s username and the specified inline query in the input field.
+	SwitchInlineQuery *string `json:"switch_inline_query,omitempty"`
+	// Optional. If set, pressing the button will insert the bot‘s username and the specified inline query
+	// in the current chat's input field.
+	SwitchInlineQueryCurrentChat *string `json:"switch_inline_query_current_chat,omitempty"`
+	// Optional. Description of the game that will be launched when the user presses the button.
+	// This type of button must always be the first button in the first row.
+	CallbackGame *CallbackGame `json:"callback_game,omitempty"`
+	// Optional. Specify True, to send a Pay button. This type of button must always be the first button
+	// in the first row.
+	Pay bool `json:"pay,omitempty"`
+}
+
+// CallbackQuery represents an incoming callback query from a callback button in an inline keyboard.
+type CallbackQuery struct {
+	// Unique identifier for this query
+	Id string `json:"id"`
+	// Sender
+	From *User `json:"from"`
+	// Optional. Message with the callback button that originated the query.
+	// Note that message content and message date will not be available if the message is too old
+	Message *Message `json:"message,omitempty"`
+	// Optional. Identifier of the message sent via the bot in inline mode, that originated the query.
+	InlineMessageId string `json:"inline_message_id,omitempty"`
+	// Global identifier, uniquely corresponding to the chat to which the message with the callback button was sent.
+	// Useful for high scores in games.
+	ChatInstance string `json:"chat_instance"`
+	// Optional. Data associated with the callback button. Be aware that a bad client can send arbitrary data
+	// in this field.
+	Data string `json:"data,omitempty"`
+	// Optional. Short name of a Game to be returned, serves as the unique identifier for the game
+	GameShortName string `json:"game_short_name,omitempty"`
+}
+
+// IsGame reports whether the query was sent by a callback_game button.
+func (q *CallbackQuery) IsGame() bool {
+	return q.GameShortName != ""
+}
+
+// PassportData contains information about Telegram Passport data shared with the bot by the user.
+type PassportData struct {
+	// Array with information about documents and other Telegram Passport elements that was shared with the bot
+	Data []EncryptedPassportElement `json:"data"`
+	// Encrypted credentials required to decrypt the data
+	Credentials *EncryptedCredentials `json:"credentials"`
+}
+
+// PassportFile represents a file uploaded to Telegram Passport. Currently all Telegram Passport files
+// are in JPEG format when decrypted and don't exceed 10MB.
+type PassportFile struct {
+	// Identifier for this file, which can be used to download or reuse the file.
+	FileId string `json:"file_id"`
+	// Unique identifier for this file, which is supposed to be the same over time and for different bots.
+	// Can't be used to download or reuse the file.
+	FileUniqueId string `json:"file_unique_id"`
+	// File size
+	FileSize int `json:"file_size"`
+	// Unix time when the file was uploaded
+	FileDate int `json:"file_date"`
+}
+
+// EncryptedPassportElement contains information about documents or other Telegram Passport elements
+// shared with the bot by the user.
+type EncryptedPassportElement struct {
+	// Element type. One of “personal_details”, “passport”, “driver_license”, “identity_card”,
+	// “internal_passport”, “address”, “utility_bill”, “bank_statement”, “rental_agreement”,
+	// “passport_registration”, “temporary_registration”, “phone_number”, “email”.
+	Type string `json:"type"`
+	// Optional. Base64-encoded encrypted Telegram Passport element data provided by the user,
+	// available for “personal_details”, “passport”, “driver_license”, “identity_card”, “internal_passport”
+	// and “address” types. Can be decrypted and verified using the accompanying EncryptedCredentials.
+	Data string `json:"data,omitempty"`
+	// Optional. User's verified phone number, available only for “phone_number” type
+	PhoneNumber string `json:"phone_number,omitempty"`
+	// Optional. User's verified email address, available only for “email” type
+	Email string `json:"email,omitempty"`
+	// Optional. Array of encrypted files with documents provided by the user, available for “utility_bill”,
+	// “bank_statement”, “rental_agreement”, “passport_registration” and “temporary_registration” types.
+	Files []PassportFile `json:"files,omitempty"`
+	// Optional. Encrypted file with the front side of the document, provided by the user.
+	// Available for “passport”, “driver_license”, “identity_card” and “internal_passport”.
+	FrontSide *PassportFile `json:"front_side,omitempty"`
+	// Optional. Encrypted file with the reverse side of the document, provided by the user.
+	// Available for “driver_license” and “identity_card”.
+	ReverseSide *PassportFile `json:"reverse_side,omitempty"`
+	// Optional. Encrypted file with the selfie of the user holding a document, provided by the user;
+	// available for “passport”, “driver_license”, “identity_card” and “internal_passport”.
+	Selfie *PassportFile `json:"selfie,omitempty"`
+	// Optional. Array of encrypted files with translated versions of documents provided by the user.
+	// Available if requested for “passport”, “driver_license”, “identity_card”, “internal_passport”,
+	// “utility_bill”, “bank_statement”, “rental_agreement”, “passport_registration” and
+	// “temporary_registration” types.
+	Translation []PassportFile `json:"translation,omitempty"`
+	// Base64-encoded element hash for using in PassportElementErrorUnspecified
+	Hash string `json:"hash"`
+}
+
+// EncryptedCredentials contains data required for decrypting and authenticating EncryptedPassportElement.
+type EncryptedCredentials struct {
+	// Base64-encoded encrypted JSON-serialized data with unique user's payload, data hashes and secrets
+	// required for EncryptedPassportElement decryption and authentication
+	Data string `json:"data"`
+	// Base64-encoded data hash for data authentication
+	Hash string `json:"hash"`
+	// Base64-encoded secret, encrypted with the bot's public RSA key, required for data decryption
+	Secret string `json:"secret"`
+}
+
+// InlineQuery represents an incoming inline query. When the user sends an empty query,
+// your bot could return some default or trending results.
+type InlineQuery struct {
+	// Unique identifier for this query
+	Id string `json:"id"`
+	// Sender
+	From *User `json:"from"`
+	// Optional. Sender location, only for bots that request user location
+	Location *Location `json:"location,omitempty"`
+	// Text of the query (up to 256 characters)
+	Query string `json:"query"`
+	// Offset of the results to be returned, can be controlled by the bot
+	Offset string `json:"offset"`
+}
+
+// ChosenInlineResult represents a result of an inline query that was chosen by the user and sent
+// to their chat partner.
+type ChosenInlineResult struct {
+	// The unique identifier for the result that was chosen
+	ResultId string `json:"result_id"`
+	// The user that chose the result
+	From *User `json:"from"`
+	// Optional. Sender location, only for bots that require user location
+	Location *Location `json:"location,omitempty"`
+	// Optional. Identifier of the sent inline message. Available only if there is an inline keyboard
+	// attached to the message.
+	InlineMessageId string `json:"inline_message_id,omitempty"`
+	// The query that was used to obtain the result
+	Query string `json:"query"`
+}
@@ -0,0 +1,122 @@
+// tgbot-go
+// https://github.com/modern-dev/tgbot-go
+// Copyright (c) 2020 Bohdan Shtepan
+// Licensed under the MIT license.
+
+package tgbot
+
+// UpdateKind is the kind of an Update, named after the field of the update that is set.
+type UpdateKind string
+
+const (
+	// UpdateAny matches updates of every kind when a handler is registered.
+	UpdateAny                UpdateKind = ""
+	UpdateMessage            UpdateKind = "message"
+	UpdateEditedMessage      UpdateKind = "edited_message"
+	UpdateChannelPost        UpdateKind = "channel_post"
+	UpdateEditedChannelPost  UpdateKind = "edited_channel_post"
+	UpdateInlineQuery        UpdateKind = "inline_query"
+	UpdateChosenInlineResult UpdateKind = "chosen_inline_result"
+	UpdateCallbackQuery      UpdateKind = "callback_query"
+	UpdateShippingQuery      UpdateKind = "shipping_query"
+	UpdatePreCheckoutQuery   UpdateKind = "pre_checkout_query"
+	UpdatePoll               UpdateKind = "poll"
+	UpdatePollAnswer         UpdateKind = "poll_answer"
+	// UpdateAlbum is the kind of updates made by a MediaGroupCollector.
+	UpdateAlbum UpdateKind = "album"
+	// UpdateUnknown is the kind of updates with none of the known fields set.
+	UpdateUnknown UpdateKind = "unknown"
+)
+
+// Kind returns the kind of the update.
+func (u *Update) Kind() UpdateKind {
+	switch {
+	case u.Message != nil:
+		return UpdateMessage
+	case u.EditedMessage != nil:
+		return UpdateEditedMessage
+	case u.ChannelPost != nil:
+		return UpdateChannelPost
+	case u.EditedChannelPost != nil:
+		return UpdateEditedChannelPost
+	case u.InlineQuery != nil:
+		return UpdateInlineQuery
+	case u.ChosenInlineResult != nil:
+		return UpdateChosenInlineResult
+	case u.CallbackQuery != nil:
+		return UpdateCallbackQuery
+	case u.ShippingQuery != nil:
+		return UpdateShippingQuery
+	case u.PreCheckoutQuery != nil:
+		return UpdatePreCheckoutQuery
+	case u.Poll != nil:
+		return UpdatePoll
+	case u.PollAnswer != nil:
+		return UpdatePollAnswer
+	case len(u.Album) > 0:
+		return UpdateAlbum
+	}
+
+	return UpdateUnknown
+}
+
+// EffectiveMessage returns the message of the update: the new or edited message or channel post,
+// the message with the button of a callback query or the first message of an album.
+// Returns nil for other kinds of updates.
+func (u *Update) EffectiveMessage() *Message {
+	switch {
+	case u.Message != nil:
+		return u.Message
+	case u.EditedMessage != nil:
+		return u.EditedMessage
+	case u.ChannelPost != nil:
+		return u.ChannelPost
+	case u.EditedChannelPost != nil:
+		return u.EditedChannelPost
+	case u.CallbackQuery != nil:
+		return u.CallbackQuery.Message
+	case len(u.Album) > 0:
+		return u.Album[0]
+	}
+
+	return nil
+}
+
+// EffectiveChat returns the chat the update belongs to, or nil if the update is not bound to a chat.
+func (u *Update) EffectiveChat() *Chat {
+	if msg := u.EffectiveMessage(); msg != nil {
+		return msg.Chat
+	}
+
+	return nil
+}
+
+// EffectiveUser returns the user who caused the update, or nil if there is none.
+func (u *Update) EffectiveUser() *User {
+	switch {
+	case u.Message != nil:
+		return u.Message.From
+	case u.EditedMessage != nil:
+		return u.EditedMessage.From
+	case u.ChannelPost != nil:
+		return u.ChannelPost.From
+	case u.EditedChannelPost != nil:
+		return u.EditedChannelPost.From
+	case u.InlineQuery != nil:
+		return u.InlineQuery.From
+	case u.ChosenInlineResult != nil:
+		return u.ChosenInlineResult.From
+	case u.CallbackQuery != nil:
+		return u.CallbackQuery.From
+	case u.ShippingQuery != nil:
+		return u.ShippingQuery.From
+	case u.PreCheckoutQuery != nil:
+		return u.PreCheckoutQuery.From
+	case u.PollAnswer != nil:
+		return u.PollAnswer.User
+	case len(u.Album) > 0:
+		return u.Album[0].From
+	}
+
+	return nil
+}